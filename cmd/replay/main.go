@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jumale/gooster/pkg/gooster"
+	"github.com/jumale/gooster/pkg/gooster/app"
+	"os"
+)
+
+func main() {
+	homeDir, _ := os.UserHomeDir()
+	cfgPath := flag.String("config", homeDir+"/.gooster.yaml", "path to the config file")
+	speed := flag.Float64("speed", 0, "replay speed relative to the recorded one, 0 replays without delays")
+	size := flag.String("size", "120x40", "size of the virtual screen")
+	unsafe := flag.Bool("unsafe", false, "handle events, which run commands or change the file system")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] <session.jsonl>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := gooster.ReplayConfig{Speed: *speed}
+	if _, err := fmt.Sscanf(*size, "%dx%d", &cfg.Width, &cfg.Height); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid screen size '%s'\n", *size)
+		os.Exit(2)
+	}
+	if !*unsafe {
+		cfg.Block = app.UnsafeEvents
+	}
+
+	session, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer session.Close()
+
+	// the app redirects stdout to its output
+	stdout := os.Stdout
+	screen, err := app.Replay(*cfgPath, session, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprint(stdout, screen)
+}
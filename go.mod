@@ -3,6 +3,7 @@ module github.com/jumale/gooster
 go 1.12
 
 require (
+	github.com/creack/pty v1.1.11
 	github.com/gdamore/tcell v1.2.0
 	github.com/oliveagle/jsonpath v0.0.0-20180606110733-2e52cf6e6852
 	github.com/pkg/errors v0.8.1
@@ -10,5 +11,6 @@ require (
 	github.com/pmezard/go-difflib v1.0.0
 	github.com/rivo/tview v0.0.0-20190829161255-f8bc69b90341
 	github.com/stretchr/testify v1.4.0
+	golang.org/x/sys v0.0.0-20190626150813-e07cf5db2756
 	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,698 @@
+package ansi
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	// the same patterns are used by tview to find color tags and escaped tags
+	colorTagPattern   = regexp.MustCompile(`^\[([a-zA-Z]+|#[0-9a-zA-Z]{6}|\-)?(:([a-zA-Z]+|#[0-9a-zA-Z]{6}|\-)?(:([lbdru]+|\-)?)?)?\]`)
+	escapedTagPattern = regexp.MustCompile(`^\[([a-zA-Z0-9_,;: \-\."#]+)\[(\[*)\]`)
+)
+
+// Terminal is a minimal terminal emulator. It keeps a virtual screen buffer,
+// which is rewritten by control characters and cursor movement sequences
+// (so that progress bars are updated in place instead of flooding the output),
+// and renders the buffer as a text with tview color tags.
+//
+// The input is expected to have the ANSI colors already converted to tview tags (see NewWriter).
+// Supported are:
+//   - carriage return, backspace, tab and line feed (which also returns the cursor to the line start);
+//   - cursor movement: CUU, CUD, CUF, CUB, CNL, CPL, CHA, CUP;
+//   - erase in line (EL) and erase in display (ED);
+//   - full reset (ESC c).
+//
+// All other escape sequences are dropped.
+//
+// The rendered lines are cached, so that only the lines changed since the previous render are rendered again.
+type Terminal struct {
+	mu    *sync.Mutex
+	lines [][]cell
+	row   int
+	col   int
+	// top is the first line of the visible screen, it moves only down as the content grows
+	// (unless the screen is resized or the content is trimmed)
+	top     int
+	height  int
+	style   style
+	pending []byte
+	cache   renderCache
+	sizes   sizeCache
+}
+
+// renderCache keeps the rendered lines of the buffer.
+type renderCache struct {
+	buf []byte
+	// end offset of every rendered line in the buffer, and the style at the end of the line
+	ends   []int
+	styles []style
+	// number of the lines, which have not been changed since they were rendered
+	valid int
+}
+
+// sizeCache keeps the size in bytes of every line of the buffer.
+type sizeCache struct {
+	sizes []int
+	total int
+	// number of the lines, which have not been changed since they were counted
+	valid int
+}
+
+// style is a set of tview tag values: foreground, background and flags.
+// Empty values stand for the defaults.
+type style [3]string
+
+type cell struct {
+	ch    rune
+	style style
+}
+
+const (
+	chBell      = 7
+	chBackspace = 8
+	chTab       = 9
+	chLineFeed  = 10
+	chReturn    = 13
+	tabWidth    = 8
+	// incomplete sequences longer than that are considered garbage
+	maxSequenceLen = 256
+)
+
+func NewTerminal() *Terminal {
+	return &Terminal{mu: &sync.Mutex{}, lines: [][]cell{{}}}
+}
+
+// SetHeight defines the height of the visible screen.
+// The cursor can not be moved above the top of the visible screen,
+// so the content scrolled out of it stays untouched.
+// Zero height (default) makes the whole buffer reachable.
+func (t *Terminal) SetHeight(height int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.height = height
+	t.top = 0
+}
+
+func (t *Terminal) Write(p []byte) (n int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := p
+	if len(t.pending) > 0 {
+		data = append(t.pending, p...)
+		t.pending = nil
+	}
+
+	for i := 0; i < len(data); {
+		consumed := t.consume(data[i:])
+		if consumed == 0 {
+			// the sequence could be split between two writes
+			if len(data)-i < maxSequenceLen {
+				t.pending = append([]byte{}, data[i:]...)
+				break
+			}
+			consumed = 1
+		}
+		i += consumed
+	}
+
+	return len(p), nil
+}
+
+// String renders the buffer as a text with tview color tags.
+func (t *Terminal) String() string {
+	return t.Render(nil)
+}
+
+// Mark wraps a part of a line (from Start to End rune positions) into the prefix and suffix
+// (e.g. region or color tags).
+type Mark struct {
+	Line   int
+	Start  int
+	End    int
+	Prefix string
+	Suffix string
+}
+
+// Render renders the buffer as a text with tview color tags, and applies the marks.
+// The marks are expected to be sorted by position and not to overlap.
+// Without marks only the lines changed since the previous render are rendered.
+func (t *Terminal) Render(marks []Mark) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	nonEmpty := marks[:0:0]
+	for _, mark := range marks {
+		if mark.End > mark.Start {
+			nonEmpty = append(nonEmpty, mark)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return t.renderCached()
+	}
+	return t.render(nonEmpty)
+}
+
+// renderCached renders the lines changed since the previous call, and reuses the rest of the previous result.
+func (t *Terminal) renderCached() string {
+	c := &t.cache
+	if c.valid > len(t.lines) {
+		c.valid = len(t.lines)
+	}
+
+	var curr style
+	if c.valid > 0 {
+		c.buf = c.buf[:c.ends[c.valid-1]]
+		curr = c.styles[c.valid-1]
+	} else {
+		c.buf = c.buf[:0]
+	}
+	c.ends, c.styles = c.ends[:c.valid], c.styles[:c.valid]
+
+	for i := c.valid; i < len(t.lines); i++ {
+		if i > 0 {
+			c.buf = append(c.buf, chLineFeed)
+		}
+		for _, cell := range t.lines[i] {
+			if cell.style != curr {
+				c.buf = append(c.buf, cell.style.tag()...)
+				curr = cell.style
+			}
+			c.buf = append(c.buf, string(cell.ch)...)
+		}
+		c.ends = append(c.ends, len(c.buf))
+		c.styles = append(c.styles, curr)
+	}
+	c.valid = len(t.lines)
+	return string(c.buf)
+}
+
+// render renders the whole buffer with the marks.
+func (t *Terminal) render(marks []Mark) string {
+	// forces the style tag to be printed, since a mark could change the colors
+	invalid := style{"\n"}
+
+	var buf strings.Builder
+	var curr style
+	for i, line := range t.lines {
+		if i > 0 {
+			buf.WriteByte(chLineFeed)
+		}
+		for j := 0; j <= len(line); j++ {
+			for len(marks) > 0 && marks[0].Line < i {
+				marks = marks[1:]
+			}
+			if len(marks) > 0 && marks[0].Line == i && marks[0].End == j {
+				buf.WriteString(marks[0].Suffix)
+				marks = marks[1:]
+				curr = invalid
+			}
+			if j == len(line) {
+				break
+			}
+			if len(marks) > 0 && marks[0].Line == i && marks[0].Start == j {
+				if curr != line[j].style {
+					buf.WriteString(line[j].style.tag())
+				}
+				buf.WriteString(marks[0].Prefix)
+				curr = line[j].style
+			} else if line[j].style != curr {
+				buf.WriteString(line[j].style.tag())
+				curr = line[j].style
+			}
+			buf.WriteRune(line[j].ch)
+		}
+	}
+	return buf.String()
+}
+
+// Text returns the buffer content as a plain text without color tags.
+func (t *Terminal) Text() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var buf strings.Builder
+	for i, line := range t.lines {
+		if i > 0 {
+			buf.WriteByte(chLineFeed)
+		}
+		for _, c := range line {
+			buf.WriteRune(c.ch)
+		}
+	}
+	return buf.String()
+}
+
+// Lines returns number of lines in the buffer, the trailing empty line is not counted.
+func (t *Terminal) Lines() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.countLines()
+}
+
+func (t *Terminal) countLines() int {
+	if len(t.lines[len(t.lines)-1]) == 0 {
+		return len(t.lines) - 1
+	}
+	return len(t.lines)
+}
+
+// Size returns number of lines and size in bytes of the text in the buffer, including a line feed after every line
+// (the trailing empty line is not counted). Only the lines changed since the previous call are counted again.
+func (t *Terminal) Size() (lines, bytes int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.countSizes()
+	lines = t.countLines()
+	return lines, t.sizes.total + lines
+}
+
+// LineSize returns size in bytes of the line, without the line feed.
+func (t *Terminal) LineSize(line int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.countSizes()
+	if line < 0 || line >= len(t.sizes.sizes) {
+		return 0
+	}
+	return t.sizes.sizes[line]
+}
+
+// countSizes counts the lines changed since the previous call.
+func (t *Terminal) countSizes() {
+	c := &t.sizes
+	if c.valid > len(t.lines) {
+		c.valid = len(t.lines)
+	}
+	for _, size := range c.sizes[c.valid:] {
+		c.total -= size
+	}
+	c.sizes = c.sizes[:c.valid]
+
+	for _, line := range t.lines[c.valid:] {
+		size := 0
+		for _, cell := range line {
+			size += utf8.RuneLen(cell.ch)
+		}
+		c.sizes = append(c.sizes, size)
+		c.total += size
+	}
+	c.valid = len(t.lines)
+}
+
+// TrimTop removes n first lines from the buffer and returns them rendered with color tags.
+// Every returned line starts with the default style.
+func (t *Terminal) TrimTop(n int) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if n > t.countLines() {
+		n = t.countLines()
+	}
+	trimmed := make([]string, 0, n)
+	for _, line := range t.lines[:n] {
+		trimmed = append(trimmed, renderLine(line))
+	}
+
+	t.lines = t.lines[n:]
+	if len(t.lines) == 0 {
+		t.lines = [][]cell{{}}
+	}
+	if t.row -= n; t.row < 0 {
+		t.row, t.col = 0, 0
+	}
+	if t.top -= n; t.top < 0 {
+		t.top = 0
+	}
+	t.trimCache(n)
+	t.trimSizes(n)
+	return trimmed
+}
+
+// trimCache removes n first lines from the render cache.
+func (t *Terminal) trimCache(n int) {
+	c := &t.cache
+	if n == 0 {
+		return
+	}
+	// the next line has been rendered after the style of the trimmed one
+	if c.valid <= n || c.styles[n-1] != (style{}) {
+		c.valid = 0
+		return
+	}
+	// the line feed before the next line is trimmed as well
+	offset := c.ends[n-1] + 1
+	c.buf = append(c.buf[:0], c.buf[offset:c.ends[c.valid-1]]...)
+	c.ends = append(c.ends[:0], c.ends[n:c.valid]...)
+	c.styles = append(c.styles[:0], c.styles[n:c.valid]...)
+	for i := range c.ends {
+		c.ends[i] -= offset
+	}
+	c.valid -= n
+}
+
+// trimSizes removes n first lines from the size cache.
+func (t *Terminal) trimSizes(n int) {
+	c := &t.sizes
+	if c.valid <= n {
+		c.sizes, c.total, c.valid = c.sizes[:0], 0, 0
+		return
+	}
+	for _, size := range c.sizes[:n] {
+		c.total -= size
+	}
+	c.sizes = c.sizes[n:]
+	c.valid -= n
+}
+
+// changed marks the line and all lines below it as changed, so that they are rendered and counted again.
+func (t *Terminal) changed(row int) {
+	if row < t.cache.valid {
+		t.cache.valid = row
+	}
+	if row < t.sizes.valid {
+		t.sizes.valid = row
+	}
+}
+
+// Clear removes all content and resets the cursor and style.
+func (t *Terminal) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reset()
+}
+
+// consume processes the first character (or sequence) of the data,
+// and returns number of processed bytes, or 0 if the sequence is incomplete.
+func (t *Terminal) consume(data []byte) int {
+	switch data[0] {
+	case chLineFeed:
+		t.row++
+		t.col = 0
+		if t.row == len(t.lines) {
+			t.lines = append(t.lines, []cell{})
+		}
+		return 1
+	case chReturn:
+		t.col = 0
+		return 1
+	case chBackspace:
+		if t.col > 0 {
+			t.col--
+		}
+		return 1
+	case chTab:
+		t.put(' ')
+		for t.col%tabWidth != 0 {
+			t.put(' ')
+		}
+		return 1
+	case chOpen:
+		return t.consumeEscape(data)
+	case '[':
+		return t.consumeTag(data)
+	}
+
+	if data[0] < ' ' {
+		// other control characters are not printable
+		return 1
+	}
+	if !utf8.FullRune(data) {
+		return 0
+	}
+	r, size := utf8.DecodeRune(data)
+	t.put(r)
+	return size
+}
+
+func (t *Terminal) consumeEscape(data []byte) int {
+	if len(data) < 2 {
+		return 0
+	}
+
+	switch data[1] {
+	case chEscape:
+		// CSI: parameter bytes, ended by a final byte
+		for i := 2; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				t.execCSI(string(data[2:i]), data[i])
+				return i + 1
+			}
+			if data[i] < 0x20 || data[i] > 0x3f {
+				// malformed sequence, drop what's been parsed so far
+				return i
+			}
+		}
+		return 0
+
+	case ']':
+		// OSC (e.g. window title): ended by BEL or ST
+		for i := 2; i < len(data); i++ {
+			if data[i] == chBell {
+				return i + 1
+			}
+			if data[i] == chOpen && i+1 < len(data) && data[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return 0
+
+	case 'c':
+		t.reset()
+		return 2
+
+	default:
+		// other sequences: optional intermediate bytes (e.g. charset selection "ESC ( B"), ended by a final byte
+		for i := 1; i < len(data); i++ {
+			if data[i] >= 0x30 && data[i] <= 0x7e {
+				return i + 1
+			}
+			if data[i] < 0x20 || data[i] > 0x2f {
+				return i
+			}
+		}
+		return 0
+	}
+}
+
+func (t *Terminal) consumeTag(data []byte) int {
+	if tag := colorTagPattern.Find(data); tag != nil {
+		t.applyTag(string(tag[1 : len(tag)-1]))
+		return len(tag)
+	}
+	if escaped := escapedTagPattern.Find(data); escaped != nil {
+		// keep the escaped tag as is, tview will unescape it
+		for _, r := range string(escaped) {
+			t.put(r)
+		}
+		return len(escaped)
+	}
+	if len(data) < maxSequenceLen && isTagPrefix(data[1:]) {
+		// the rest of the tag could come with the next write
+		return 0
+	}
+	t.put('[')
+	return 1
+}
+
+func (t *Terminal) applyTag(tag string) {
+	for i, value := range strings.SplitN(tag, ":", 3) {
+		switch value {
+		case "":
+		case "-":
+			t.style[i] = ""
+		default:
+			t.style[i] = value
+		}
+	}
+}
+
+func (t *Terminal) execCSI(params string, cmd byte) {
+	if strings.HasPrefix(params, "?") {
+		// private modes (cursor visibility, bracketed paste, etc) are not supported
+		return
+	}
+
+	args := strings.Split(params, ";")
+	arg := func(i, def int) int {
+		if i >= len(args) {
+			return def
+		}
+		val, err := strconv.Atoi(args[i])
+		if err != nil || val == 0 {
+			return def
+		}
+		return val
+	}
+
+	switch cmd {
+	case 'A':
+		t.moveTo(t.row-arg(0, 1), t.col)
+	case 'B':
+		t.moveTo(t.row+arg(0, 1), t.col)
+	case 'C':
+		t.moveTo(t.row, t.col+arg(0, 1))
+	case 'D':
+		t.moveTo(t.row, t.col-arg(0, 1))
+	case 'E':
+		t.moveTo(t.row+arg(0, 1), 0)
+	case 'F':
+		t.moveTo(t.row-arg(0, 1), 0)
+	case 'G':
+		t.moveTo(t.row, arg(0, 1)-1)
+	case 'H', 'f':
+		t.moveTo(t.screenTop()+arg(0, 1)-1, arg(1, 1)-1)
+	case 'K':
+		t.eraseLine(arg(0, 0))
+	case 'J':
+		t.eraseDisplay(arg(0, 0))
+	}
+}
+
+// screenTop returns index of the first line of the visible screen.
+func (t *Terminal) screenTop() int {
+	if t.height <= 0 {
+		return 0
+	}
+	if top := len(t.lines) - t.height; top > t.top {
+		t.top = top
+	}
+	return t.top
+}
+
+// moveTo moves the cursor within the visible screen,
+// new lines are added if the cursor is moved below the last one.
+func (t *Terminal) moveTo(row, col int) {
+	top := t.screenTop()
+	bottom := len(t.lines) - 1
+	if t.height > 0 && top+t.height-1 > bottom {
+		bottom = top + t.height - 1
+	}
+	if row < top {
+		row = top
+	} else if row > bottom {
+		row = bottom
+	}
+	if col < 0 {
+		col = 0
+	}
+	for row >= len(t.lines) {
+		t.lines = append(t.lines, []cell{})
+	}
+	t.row, t.col = row, col
+}
+
+func (t *Terminal) put(r rune) {
+	line := t.lines[t.row]
+	for len(line) < t.col {
+		line = append(line, cell{ch: ' '})
+	}
+	c := cell{ch: r, style: t.style}
+	if t.col < len(line) {
+		line[t.col] = c
+	} else {
+		line = append(line, c)
+	}
+	t.lines[t.row] = line
+	t.col++
+	t.changed(t.row)
+}
+
+func (t *Terminal) eraseLine(mode int) {
+	t.changed(t.row)
+	line := t.lines[t.row]
+	switch mode {
+	case 0: // from cursor to the end of line
+		if t.col < len(line) {
+			t.lines[t.row] = line[:t.col]
+		}
+	case 1: // from the beginning of line to cursor
+		for i := 0; i <= t.col && i < len(line); i++ {
+			line[i] = cell{ch: ' '}
+		}
+	case 2: // the whole line
+		t.lines[t.row] = line[:0]
+	}
+}
+
+func (t *Terminal) eraseDisplay(mode int) {
+	top := t.screenTop()
+	switch mode {
+	case 0: // from cursor to the end of screen
+		t.eraseLine(0)
+		t.lines = t.lines[:t.row+1]
+	case 1: // from the beginning of screen to cursor
+		t.changed(top)
+		for i := top; i < t.row; i++ {
+			t.lines[i] = t.lines[i][:0]
+		}
+		t.eraseLine(1)
+	case 2: // the whole screen: the lines scrolled out of it are kept, the cursor stays in place
+		t.changed(top)
+		for i := top; i <= t.row; i++ {
+			t.lines[i] = t.lines[i][:0]
+		}
+		// the empty lines below the cursor would only pad the output
+		t.lines = t.lines[:t.row+1]
+	case 3: // the lines scrolled out of the screen
+		t.changed(0)
+		t.lines = t.lines[top:]
+		t.row -= top
+		t.top = 0
+	}
+}
+
+func (t *Terminal) reset() {
+	t.lines = [][]cell{{}}
+	t.row, t.col, t.top = 0, 0, 0
+	t.style = style{}
+	t.pending = nil
+	t.changed(0)
+}
+
+func renderLine(line []cell) string {
+	var buf strings.Builder
+	var curr style
+	for _, c := range line {
+		if c.style != curr {
+			buf.WriteString(c.style.tag())
+			curr = c.style
+		}
+		buf.WriteRune(c.ch)
+	}
+	if curr != (style{}) {
+		buf.WriteString(style{}.tag())
+	}
+	return buf.String()
+}
+
+func isTagPrefix(data []byte) bool {
+	for _, b := range data {
+		isLetter := (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+		isDigit := b >= '0' && b <= '9'
+		if !isLetter && !isDigit && b != '#' && b != ':' && b != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func (s style) tag() string {
+	vals := [3]string{}
+	for i, val := range s {
+		if val == "" {
+			val = "-"
+		}
+		vals[i] = val
+	}
+	return "[" + strings.Join(vals[:], ":") + "]"
+}
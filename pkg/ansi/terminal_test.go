@@ -0,0 +1,181 @@
+package ansi
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTerminal(t *testing.T) {
+	assert := require.New(t)
+
+	write := func(term *Terminal, chunks ...string) string {
+		for _, chunk := range chunks {
+			_, err := term.Write([]byte(chunk))
+			assert.NoError(err)
+		}
+		return term.String()
+	}
+
+	t.Run("should write plain text", func(t *testing.T) {
+		assert.Equal("foo\nbar\n", write(NewTerminal(), "foo\nbar\n"))
+	})
+
+	t.Run("should rewrite the line on carriage return", func(t *testing.T) {
+		assert.Equal("100%\n", write(NewTerminal(), "10%\r50%\r100%\n"))
+	})
+
+	t.Run("should handle backspace", func(t *testing.T) {
+		assert.Equal("fox", write(NewTerminal(), "foo\bx"))
+	})
+
+	t.Run("should expand tabs", func(t *testing.T) {
+		assert.Equal("foo     bar", write(NewTerminal(), "foo\tbar"))
+	})
+
+	t.Run("should erase line", func(t *testing.T) {
+		assert.Equal("foo", write(NewTerminal(), "foobar\033[3D\033[K"))
+		assert.Equal("    ar", write(NewTerminal(), "foobar\033[3D\033[1K"))
+		assert.Equal("baz", write(NewTerminal(), "foobar\033[2K\rbaz"))
+	})
+
+	t.Run("should move cursor up and down", func(t *testing.T) {
+		assert.Equal(
+			"layer1: done\nlayer2: 50%\n",
+			write(NewTerminal(), "layer1: 10%\nlayer2: 10%\n", "\033[2A\033[2Klayer1: done\033[B\rlayer2: 50%\033[1E"),
+		)
+	})
+
+	t.Run("should move cursor to column", func(t *testing.T) {
+		assert.Equal("fooXar", write(NewTerminal(), "foobar\033[4GX"))
+	})
+
+	t.Run("should not move cursor above the visible screen", func(t *testing.T) {
+		term := NewTerminal()
+		term.SetHeight(2)
+		assert.Equal("foo\nbaz\nqux", write(term, "foo\nbar\nqux", "\033[5Fbaz"))
+	})
+
+	t.Run("should position cursor relative to the visible screen", func(t *testing.T) {
+		term := NewTerminal()
+		term.SetHeight(2)
+		assert.Equal("foo\nbar\nXaz", write(term, "foo\nbar\nbaz", "\033[2;1HX"))
+	})
+
+	t.Run("should erase display", func(t *testing.T) {
+		assert.Equal("foo\nb", write(NewTerminal(), "foo\nbar\nbaz\033[A\033[2D\033[J"))
+		assert.Equal("bar", write(NewTerminal(), "foo\033[H\033[2Jbar"))
+		assert.Equal("bar", write(NewTerminal(), "foo\033cbar"))
+	})
+
+	t.Run("should erase only the visible screen", func(t *testing.T) {
+		term := NewTerminal()
+		term.SetHeight(2)
+		assert.Equal("foo\nqux", write(term, "foo\nbar\nbaz", "\033[H\033[2Jqux"))
+		assert.Equal("foo\nqux\nquux", write(term, "\033[2;1Hquux"), "should keep the screen in place")
+	})
+
+	t.Run("should erase the lines scrolled out of the screen", func(t *testing.T) {
+		term := NewTerminal()
+		term.SetHeight(2)
+		assert.Equal("bar\nbaz", write(term, "foo\nbar\nbaz\033[3J"))
+	})
+
+	t.Run("should keep colors", func(t *testing.T) {
+		assert.Equal("[red:-:-]foo[-:-:-]bar", write(NewTerminal(), "[red]foo[-]bar"))
+		assert.Equal("[red:-:-]foo[red:blue:-]bar", write(NewTerminal(), "[red]foo[:blue]bar"))
+	})
+
+	t.Run("should keep colors of overwritten text", func(t *testing.T) {
+		assert.Equal("[red:-:-]fo[-:-:-]x", write(NewTerminal(), "[red]foo[-]\bx"))
+	})
+
+	t.Run("should keep escaped tags and brackets", func(t *testing.T) {
+		assert.Equal("[foo[] [1] Done", write(NewTerminal(), "[foo[] [1] Done"))
+	})
+
+	t.Run("should drop unsupported escape sequences", func(t *testing.T) {
+		assert.Equal("foobar", write(NewTerminal(), "\033[?25lfoo\033]0;title\007bar\033(B"))
+	})
+
+	t.Run("should render marks", func(t *testing.T) {
+		term := NewTerminal()
+		_, _ = term.Write([]byte("foo [red]bar[-]\nbaz"))
+		assert.Equal(
+			`f<oo [red:-:-]b>[red:-:-]ar`+"\n"+`[-:-:-]<baz>`,
+			term.Render([]Mark{
+				{Line: 0, Start: 1, End: 5, Prefix: "<", Suffix: ">"},
+				{Line: 1, Start: 0, End: 3, Prefix: "<", Suffix: ">"},
+				{Line: 1, Start: 1, End: 1, Prefix: "(", Suffix: ")"},
+			}),
+		)
+	})
+
+	t.Run("should trim top lines", func(t *testing.T) {
+		term := NewTerminal()
+		_, _ = term.Write([]byte("[red]foo\nbär[-]\nbaz"))
+		assert.Equal(4, term.LineSize(1))
+
+		assert.Equal([]string{"[red:-:-]foo[-:-:-]", "[red:-:-]bär[-:-:-]"}, term.TrimTop(2))
+		assert.Equal("baz!", write(term, "!"))
+		assert.Equal([]string{"baz!"}, term.TrimTop(5))
+		assert.Equal("", term.String())
+	})
+
+	t.Run("should count the size of the changed lines", func(t *testing.T) {
+		term := NewTerminal()
+		_, _ = term.Write([]byte("[red]foo\nbär[-]\nbaz"))
+		lines, bytes := term.Size()
+		assert.Equal(3, lines)
+		assert.Equal(13, bytes)
+
+		_, _ = term.Write([]byte("\rqux!\n"))
+		lines, bytes = term.Size()
+		assert.Equal(3, lines)
+		assert.Equal(14, bytes)
+
+		_, _ = term.Write([]byte("\033[2A\033[K"))
+		lines, bytes = term.Size()
+		assert.Equal(3, lines)
+		assert.Equal(10, bytes)
+
+		term.TrimTop(1)
+		lines, bytes = term.Size()
+		assert.Equal(2, lines)
+		assert.Equal(6, bytes)
+		assert.Equal(4, term.LineSize(1))
+	})
+
+	t.Run("should render only the lines changed since the previous render", func(t *testing.T) {
+		term := NewTerminal()
+		write(term, "[red]foo\nbar[-]\nbaz")
+		assert.Equal(3, term.cache.valid)
+
+		write(term, "!")
+		assert.Equal(3, term.cache.valid)
+		_, _ = term.Write([]byte("\033[A\rqux"))
+		assert.Equal(1, term.cache.valid)
+		assert.Equal("[red:-:-]foo\n[-:-:-]qux\nbaz!", term.String())
+	})
+
+	t.Run("should render the same text as the full render", func(t *testing.T) {
+		term := NewTerminal()
+		term.SetHeight(3)
+		chunks := []string{
+			"[red]foo\nb", "ar[-]\nbaz\n", "10%\r50%", "\033[2A\033[2K[blue]up", "\033[2B\n[-]done\n",
+			"\033[H\033[2Jclear\n", "\033[1Jx\033[J",
+		}
+		for i, chunk := range chunks {
+			write(term, chunk)
+			assert.Equal(term.render(nil), term.String(), "after chunk %d", i)
+			if i%2 == 1 {
+				term.TrimTop(1)
+				assert.Equal(term.render(nil), term.String(), "after trimming chunk %d", i)
+			}
+		}
+	})
+
+	t.Run("should handle sequences split between writes", func(t *testing.T) {
+		assert.Equal("[red:-:-]foo\nbaz", write(NewTerminal(), "[re", "d]foo\nbar\033[", "2K\rbaz"))
+		assert.Equal("ü", write(NewTerminal(), "\xc3", "\xbc"))
+	})
+}
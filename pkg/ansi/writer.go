@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/gdamore/tcell"
 	"io"
-	"strconv"
 	"strings"
 )
 
@@ -22,14 +21,17 @@ type WriterConfig struct {
 }
 
 type writer struct {
-	target    io.Writer
-	colorMap  map[ColorId]colorValue
-	resetFg   string
-	resetBg   string
-	resetFl   string
-	state     state
-	currCode  []byte
-	tagValues [3]string
+	target   io.Writer
+	colorMap map[ColorId]colorValue
+	resetFg  string
+	resetBg  string
+	resetFl  string
+	state    state
+	currCode int
+	hasCode  bool
+	params   []int
+	// raw bytes of the current sequence, they are passed as is, if it's not a color sequence
+	seq []byte
 }
 
 func NewWriter(target io.Writer, cfg WriterConfig) io.Writer {
@@ -63,22 +65,29 @@ func NewWriter(target io.Writer, cfg WriterConfig) io.Writer {
 }
 
 // Converts all ASCII colors in the text to corresponding "tview" tags.
+// A sequence can be split between several writes: the incomplete tail is held
+// until the rest of the sequence arrives.
 func (f *writer) Write(data []byte) (int, error) {
-	var buf, tmp []byte
+	buf := make([]byte, 0, len(data))
 
 	reset := func() {
+		// not a color sequence, so it's passed as is
 		f.state = closed
-		buf = append(buf, tmp...)
-		tmp = []byte{}
+		f.currCode, f.hasCode = 0, false
+		f.params = f.params[:0]
+		buf = append(buf, f.seq...)
+		f.seq = f.seq[:0]
 	}
-	parseCode := func() {
-		f.tagValues = f.applyColorCode(f.currCode, f.tagValues)
-		f.currCode = []byte{}
+	nextParam := func() {
+		if f.hasCode {
+			f.params = append(f.params, f.currCode)
+			f.currCode, f.hasCode = 0, false
+		}
 	}
 
 	for _, b := range data {
 		if f.state == open {
-			tmp = append(tmp, b)
+			f.seq = append(f.seq, b)
 			if b == chEscape {
 				f.state = escaped
 			} else {
@@ -86,28 +95,29 @@ func (f *writer) Write(data []byte) (int, error) {
 			}
 
 		} else if f.state == escaped {
-			tmp = append(tmp, b)
+			f.seq = append(f.seq, b)
 			if b == chDiv {
-				if len(f.currCode) > 0 {
-					parseCode()
-				}
+				nextParam()
 			} else if b == chClose {
-				if len(f.currCode) > 0 {
-					parseCode()
-					buf = append(buf, f.createTag(f.tagValues[:]...)...)
-					f.tagValues = [3]string{}
+				if f.hasCode {
+					nextParam()
+					tagVals := f.applyColorCodes(f.params)
+					buf = append(buf, f.createTag(tagVals[:]...)...)
 					f.state = closed
+					f.params = f.params[:0]
+					f.seq = f.seq[:0]
 				} else {
 					reset()
 				}
 			} else if 48 <= b && b <= 57 {
-				f.currCode = append(f.currCode, b)
+				f.currCode = f.currCode*10 + int(b-48)
+				f.hasCode = true
 			} else {
 				reset()
 			}
 
 		} else if b == chOpen {
-			tmp = append(tmp, b)
+			f.seq = append(f.seq, b)
 			f.state = open
 
 		} else {
@@ -116,23 +126,43 @@ func (f *writer) Write(data []byte) (int, error) {
 
 	}
 
-	return f.target.Write(buf)
-
-	//return f.target.Write(colorTagRegExp.ReplaceAllFunc(data, func(data []byte) []byte {
-	//	var tagValues [3]string
-	//	for _, value := range bytes.Split(data[2:len(data)-1], []byte(";")) {
-	//		f.applyColorCode(value, tagValues)
-	//	}
-	//	return f.createTag(tagValues[:]...)
-	//}))
+	if _, err := f.target.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(data), nil
 }
 
-func (f *writer) applyColorCode(code []byte, tagVals [3]string) [3]string {
-	id, err := strconv.Atoi(string(code))
-	if err != nil {
-		return tagVals
+func (f *writer) applyColorCodes(codes []int) (tagVals [3]string) {
+	for i := 0; i < len(codes); i++ {
+		id := codes[i]
+
+		// extended colors: "38;5;n" (256 colors) and "38;2;r;g;b" (24-bit), the same for background with 48
+		if (id == 38 || id == 48) && i+1 < len(codes) {
+			pos := 0
+			if id == 48 {
+				pos = 1
+			}
+			switch codes[i+1] {
+			case 5:
+				if i+2 < len(codes) {
+					tagVals[pos] = f.paletteColor(codes[i+2])
+				}
+				i += 2
+			case 2:
+				if i+4 < len(codes) {
+					tagVals[pos] = fmt.Sprintf("#%02x%02x%02x", codes[i+2]&0xff, codes[i+3]&0xff, codes[i+4]&0xff)
+				}
+				i += 4
+			}
+			continue
+		}
+
+		tagVals = f.applyColorCode(id, tagVals)
 	}
+	return tagVals
+}
 
+func (f *writer) applyColorCode(id int, tagVals [3]string) [3]string {
 	switch id {
 	case 0:
 		tagVals[0] = f.resetFg
@@ -141,7 +171,7 @@ func (f *writer) applyColorCode(code []byte, tagVals [3]string) [3]string {
 	case 39:
 		tagVals[0] = f.resetFg
 	case 49:
-		tagVals[1] = f.resetFg
+		tagVals[1] = f.resetBg
 	case 21, 22, 24, 25, 27:
 		tagVals[2] = f.resetFl
 	case 1, 2, 4, 5, 7:
@@ -155,6 +185,34 @@ func (f *writer) applyColorCode(code []byte, tagVals [3]string) [3]string {
 	return tagVals
 }
 
+// paletteColor converts an index of the 256-color palette to a tview color.
+// The first 16 colors are the same as the basic ones (including the custom color map).
+func (f *writer) paletteColor(idx int) colorValue {
+	switch {
+	case idx > 255:
+		return ""
+	case idx < 8:
+		return f.colorMap[30+idx]
+	case idx < 16:
+		return f.colorMap[90+idx-8]
+	case idx < 232:
+		// 6x6x6 color cube
+		idx -= 16
+		return fmt.Sprintf("#%02x%02x%02x", cubeLevel(idx/36), cubeLevel(idx/6%6), cubeLevel(idx%6))
+	default:
+		// grayscale ramp
+		level := 8 + (idx-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", level, level, level)
+	}
+}
+
+func cubeLevel(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return 55 + n*40
+}
+
 func (f *writer) createTag(values ...string) []byte {
 	if values[2] == "" {
 		values = values[:len(values)-1]
@@ -82,6 +82,36 @@ func TestAsciiToTviewColors(t *testing.T) {
 	t.Run("don't be greedy, stop at the first closing case", func(t *testing.T) {
 		assert.Equal("foo [:red:b]97mbar", write("foo \033[1;41m97mbar"))
 	})
+
+	t.Run("convert 256 colors", func(t *testing.T) {
+		assert.Equal(`[red]foo`, write("\033[38;5;1mfoo"))
+		assert.Equal(`[white]foo`, write("\033[38;5;15mfoo"))
+		assert.Equal(`[:#5f87af]foo`, write("\033[48;5;67mfoo"))
+		assert.Equal(`[#808080]foo`, write("\033[38;5;244mfoo"))
+	})
+
+	t.Run("convert truecolor", func(t *testing.T) {
+		assert.Equal(`[#ff8000]foo`, write("\033[38;2;255;128;0mfoo"))
+		assert.Equal(`[#010203:#0a0b0c:b]foo`, write("\033[1;38;2;1;2;3;48;2;10;11;12mfoo"))
+	})
+
+	t.Run("convert extended colors mixed with basic codes", func(t *testing.T) {
+		assert.Equal(`[#ff8000:blue:u]foo`, write("\033[4;38;2;255;128;0;44mfoo"))
+	})
+
+	t.Run("handle sequences split between writes", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		writer := NewWriter(buf, WriterConfig{DefaultFg: tcell.ColorDefault, DefaultBg: tcell.ColorDefault})
+		for _, chunk := range []string{"foo \033", "[38;2;255", ";128;0", "mbar \033[", "0m"} {
+			_, err := writer.Write([]byte(chunk))
+			assert.NoError(err)
+		}
+		assert.Equal(`foo [#ff8000]bar [-:-:-]`, buf.String())
+	})
+
+	t.Run("don't mix codes of a non-color sequence into the next one", func(t *testing.T) {
+		assert.Equal("\033[2K[red]foo", write("\033[2K\033[31mfoo"))
+	})
 }
 
 func createWriteTester(t *testing.T, cfg WriterConfig) func(string) string {
@@ -0,0 +1,176 @@
+package command
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+)
+
+var HeredocErr = errors.New("Non closed heredoc")
+
+// Line is a command line of a multi-line input.
+type Line struct {
+	// Command is the text of the line, it contains line breaks of the multi-line quoted strings,
+	// but not the line continuations
+	Command string
+	// Heredoc is the content of the heredocs opened by the line, including their closing delimiters
+	Heredoc string
+}
+
+type heredoc struct {
+	delimiter string
+	// the leading tabs of the content are removed (the <<- operator)
+	stripTabs bool
+}
+
+// SplitLines splits the input into the command lines. The line breaks within quotes are kept in the lines,
+// as well as the content of heredocs. The heredoc operators are recognized outside of quotes and arithmetic
+// expressions only. It returns QuoteErr if the quotes are not closed, and HeredocErr if a heredoc is not closed.
+func SplitLines(input string) (lines []Line, err error) {
+	runes := []rune(input)
+	var current []rune
+	var heredocs []heredoc
+	var quoteSign rune
+	var escaped bool
+	// depth of the arithmetic expressions $(( ... )) and (( ... ))
+	arithmetic := 0
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			escaped = false
+			if r == '\n' {
+				// the line continuation is removed, like bash does it
+				current = current[:len(current)-1]
+				continue
+			}
+		case quoteSign != 0:
+			if r == quoteSign {
+				quoteSign = 0
+			} else if r == escape && quoteSign == dQuote {
+				escaped = true
+			}
+		case r == escape:
+			escaped = true
+		case r == quote || r == dQuote:
+			quoteSign = r
+		case r == '(' && next(runes, i) == '(':
+			arithmetic++
+			current = append(current, r, r)
+			i++
+			continue
+		case r == ')' && next(runes, i) == ')' && arithmetic > 0:
+			arithmetic--
+			current = append(current, r, r)
+			i++
+			continue
+		case r == '<' && next(runes, i) == '<' && next(runes, i+1) == '<':
+			// the here-string is not a heredoc
+			current = append(current, r, r, r)
+			i += 2
+			continue
+		case r == '<' && next(runes, i) == '<' && arithmetic == 0:
+			var doc heredoc
+			doc, i = readHeredoc(runes, i, &current)
+			if doc.delimiter != "" {
+				heredocs = append(heredocs, doc)
+			}
+			continue
+		case r == '\n':
+			line := Line{Command: string(current)}
+			if line.Heredoc, i, err = readHeredocContent(runes, i+1, heredocs); err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
+			current, heredocs = nil, nil
+			continue
+		}
+		current = append(current, r)
+	}
+
+	if quoteSign != 0 {
+		return nil, QuoteErr
+	}
+	if len(heredocs) > 0 {
+		return nil, HeredocErr
+	}
+	if len(current) > 0 || len(lines) == 0 {
+		lines = append(lines, Line{Command: string(current)})
+	}
+	return lines, nil
+}
+
+func next(runes []rune, i int) rune {
+	if i+1 < len(runes) {
+		return runes[i+1]
+	}
+	return 0
+}
+
+// readHeredoc reads the heredoc operator, which starts at the position, and its delimiter.
+// It returns the position of the last read rune.
+func readHeredoc(runes []rune, i int, current *[]rune) (doc heredoc, last int) {
+	start := i
+	i += 2
+	if i < len(runes) && runes[i] == '-' {
+		doc.stripTabs = true
+		i++
+	}
+	for i < len(runes) && (runes[i] == space || runes[i] == '\t') {
+		i++
+	}
+
+	var delimiter []rune
+	var quoteSign rune
+	for ; i < len(runes); i++ {
+		r := runes[i]
+		if quoteSign == 0 && strings.ContainsRune(" \t\n;|&<>()", r) {
+			break
+		}
+		switch {
+		case r == quoteSign:
+			quoteSign = 0
+		case quoteSign == 0 && (r == quote || r == dQuote):
+			quoteSign = r
+		case quoteSign == 0 && r == escape:
+			// the escaped delimiter is just quoted
+		default:
+			delimiter = append(delimiter, r)
+		}
+	}
+
+	*current = append(*current, runes[start:i]...)
+	doc.delimiter = string(delimiter)
+	return doc, i - 1
+}
+
+// readHeredocContent reads the content of the heredocs, which starts at the position.
+// It returns the position of the line break after the last delimiter (or before the start if there are no heredocs).
+func readHeredocContent(runes []rune, i int, heredocs []heredoc) (content string, last int, err error) {
+	if len(heredocs) == 0 {
+		return "", i - 1, nil
+	}
+
+	var lines []string
+	rest := strings.Split(string(runes[i:]), "\n")
+	pos := i
+	for _, doc := range heredocs {
+		for {
+			if len(rest) == 0 {
+				return "", 0, HeredocErr
+			}
+			line := rest[0]
+			rest = rest[1:]
+			lines = append(lines, line)
+			pos += len([]rune(line)) + 1
+
+			if doc.stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == doc.delimiter {
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), pos - 1, nil
+}
@@ -0,0 +1,53 @@
+package command
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should split input into command lines", func(t *testing.T) {
+		lines, err := SplitLines("cd foo\nls -la")
+		assert.NoError(err)
+		assert.Equal([]Line{{Command: "cd foo"}, {Command: "ls -la"}}, lines)
+	})
+
+	t.Run("should keep line breaks within quotes", func(t *testing.T) {
+		lines, err := SplitLines("echo 'foo\nbar' \"baz\nqux\"\nls")
+		assert.NoError(err)
+		assert.Equal([]Line{{Command: "echo 'foo\nbar' \"baz\nqux\""}, {Command: "ls"}}, lines)
+	})
+
+	t.Run("should remove line continuations", func(t *testing.T) {
+		lines, err := SplitLines("ls -la \\\n| grep \"foo\\\nbar\"\necho 'baz\\\n'")
+		assert.NoError(err)
+		assert.Equal([]Line{{Command: "ls -la | grep \"foobar\""}, {Command: "echo 'baz\\\n'"}}, lines)
+	})
+
+	t.Run("should keep content of heredocs", func(t *testing.T) {
+		lines, err := SplitLines("cat <<EOF > foo\ndon't\nEOF\ncat <<-'END'\n\tbar\n\tEND")
+		assert.NoError(err)
+		assert.Equal([]Line{
+			{Command: "cat <<EOF > foo", Heredoc: "don't\nEOF"},
+			{Command: "cat <<-'END'", Heredoc: "\tbar\n\tEND"},
+		}, lines)
+	})
+
+	t.Run("should ignore shift operators and quoted operators", func(t *testing.T) {
+		for _, input := range []string{`echo $((1<<4))`, `(( x = 1 << 2 ))`, `echo "a << b"`, `echo 'a <<b'`, `cat <<< foo`} {
+			lines, err := SplitLines(input)
+			assert.NoError(err, input)
+			assert.Equal([]Line{{Command: input}}, lines)
+		}
+	})
+
+	t.Run("should return errors for non closed quotes and heredocs", func(t *testing.T) {
+		_, err := SplitLines("echo 'foo\nbar")
+		assert.Equal(QuoteErr, err)
+
+		_, err = SplitLines("cat <<EOF\nfoo")
+		assert.Equal(HeredocErr, err)
+	})
+}
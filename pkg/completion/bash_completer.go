@@ -29,6 +29,8 @@ const (
 type BashCompleterConfig struct {
 	CompleteBin string
 	CompgenBin  string
+	// WorkDir is the dir, in which the completions are generated (the process work dir if empty)
+	WorkDir string
 }
 
 type BashCompleter struct {
@@ -45,6 +47,13 @@ func NewBashCompleter(cfg BashCompleterConfig) *BashCompleter {
 	return &BashCompleter{cfg: cfg}
 }
 
+// InDir returns a copy of the completer, which generates the completions in the dir.
+func (b *BashCompleter) InDir(dir string) *BashCompleter {
+	cfg := b.cfg
+	cfg.WorkDir = dir
+	return &BashCompleter{cfg: cfg}
+}
+
 func (b *BashCompleter) Get(cmd command.Definition) (Completion, error) {
 	if len(cmd.Args) == 0 {
 		return b.completeCommand(cmd.Command)
@@ -91,6 +100,7 @@ func (b *BashCompleter) compgen(arg string, flags ...rune) ([]string, error) {
 
 func (b *BashCompleter) getOutput(cmd string) ([]byte, error) {
 	c := exec.Command("bash", "-l", "-c", cmd)
+	c.Dir = b.cfg.WorkDir
 
 	stdout := bytes.NewBuffer(nil)
 	c.Stdout = stdout
@@ -100,7 +110,10 @@ func (b *BashCompleter) getOutput(cmd string) ([]byte, error) {
 
 	err := c.Run()
 	if err != nil {
-		wd, _ := os.Getwd()
+		wd := b.cfg.WorkDir
+		if wd == "" {
+			wd, _ = os.Getwd()
+		}
 		return nil, errors.WithMessagef(err, "Failed compgen. Work dir: %s, Stderr: %s", wd, stderr.String())
 	}
 	return bytes.Trim(stdout.Bytes(), "\n"), nil
@@ -113,6 +126,7 @@ func (b *BashCompleter) getOutputLines(cmd string) ([]string, error) {
 
 func (b *BashCompleter) findCustomCompletion(cmd string) string {
 	c := exec.Command("bash", "-l", "-c", fmt.Sprintf(`%s -p "%s"`, b.cfg.CompleteBin, cmd))
+	c.Dir = b.cfg.WorkDir
 	stdout := bytes.NewBuffer(nil)
 	c.Stdout = stdout
 
@@ -45,6 +45,11 @@ func (k Key) RmMod(m tcell.ModMask) Key {
 	return k
 }
 
+// Matches tells whether the key event is produced by the key. Runes are compared only for the rune keys.
+func (k Key) Matches(event *tcell.EventKey) bool {
+	return k.Type == event.Key() && k.Mod == event.Modifiers() && (k.Type != tcell.KeyRune || k.Rune == event.Rune())
+}
+
 func (k Key) String() string {
 	return strings.ReplaceAll(tcell.NewEventKey(k.Type, k.Rune, k.Mod).Name(), "+", "-")
 }
@@ -30,6 +30,13 @@ func TestKey(t *testing.T) {
 		}
 	})
 
+	t.Run("Matches", func(t *testing.T) {
+		assert.True(NewKey(tcell.KeyCtrlR).Matches(tcell.NewEventKey(tcell.KeyCtrlR, 0, tcell.ModCtrl)))
+		assert.True(NewKey(tcell.KeyRune).SetRune('r').AddMod(tcell.ModAlt).Matches(tcell.NewEventKey(tcell.KeyRune, 'r', tcell.ModAlt)))
+		assert.False(NewKey(tcell.KeyRune).SetRune('r').AddMod(tcell.ModAlt).Matches(tcell.NewEventKey(tcell.KeyRune, 'r', 0)))
+		assert.False(NewKey(tcell.KeyRune).SetRune('r').Matches(tcell.NewEventKey(tcell.KeyRune, 't', 0)))
+	})
+
 	t.Run("String", func(t *testing.T) {
 		testCases := []struct {
 			val Key
@@ -0,0 +1,166 @@
+package dialog
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/config"
+	"github.com/jumale/gooster/pkg/log"
+	"github.com/rivo/tview"
+	"strings"
+)
+
+// Search is a dialog with a query field and a list of the items found by the query.
+// The list is updated, while the query is typed.
+type Search struct {
+	Title string
+	Query string
+	Width int
+	// Height is the number of the visible items
+	Height int
+	// Toggles are the options of the search, which are switched by their keys.
+	Toggles []Toggle
+	// Find returns the items matching the query, the items may contain color tags.
+	// The states of the toggles are passed in the same order.
+	Find func(query string, toggles []bool) []string
+	// Actions are called with index of the selected item, when their keys are pressed.
+	// The dialog is closed before an action is called.
+	Actions map[config.Key]func(index int)
+	// Next and Prev move the selection in addition to Down and Up keys.
+	Next     []config.Key
+	Prev     []config.Key
+	Selected config.Color
+	Log      log.Logger
+}
+
+// Toggle is an option of the search, the title of the enabled option is shown in the dialog title.
+type Toggle struct {
+	Key   config.Key
+	Title string
+	On    bool
+}
+
+func (d Search) View(cfg Config, onDone ActionHandler) tview.Primitive {
+	if d.Width == 0 {
+		d.Width = maxWidth
+	}
+	if d.Height == 0 {
+		d.Height = maxHeight
+	}
+
+	list := tview.NewTextView()
+	list.SetDynamicColors(true)
+	list.SetWrap(false)
+	list.SetBackgroundColor(cfg.Colors.Bg.Origin())
+
+	var items []string
+	selected := 0
+	render := func() {
+		var text strings.Builder
+		for i, item := range items {
+			if i == selected {
+				_, _ = fmt.Fprintf(&text, "[:#%06x]%s[:-]\n", d.Selected.Origin().Hex(), item)
+			} else {
+				text.WriteString(item + "\n")
+			}
+		}
+		list.SetText(text.String())
+		// keep the selected item visible
+		if offset := selected - d.Height + 1; offset > 0 {
+			list.ScrollTo(offset, 0)
+		} else {
+			list.ScrollTo(0, 0)
+		}
+	}
+	move := func(step int) {
+		if len(items) > 0 {
+			selected = ((selected+step)%len(items) + len(items)) % len(items)
+			render()
+		}
+	}
+
+	toggles := make([]bool, len(d.Toggles))
+	for i, toggle := range d.Toggles {
+		toggles[i] = toggle.On
+	}
+
+	box := tview.NewGrid()
+	field := tview.NewInputField()
+	field.SetLabel("> ")
+	field.SetBackgroundColor(cfg.Colors.Bg.Origin())
+	field.SetFieldBackgroundColor(cfg.Colors.Bg.Origin())
+	field.SetChangedFunc(func(query string) {
+		items, selected = d.Find(query, toggles), 0
+		render()
+	})
+	field.SetText(d.Query)
+	field.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		for key, action := range d.Actions {
+			if key.Matches(event) {
+				if len(items) > 0 {
+					index := selected
+					onDone(nil)
+					action(index)
+				}
+				return nil
+			}
+		}
+		for i, toggle := range d.Toggles {
+			if toggle.Key.Matches(event) {
+				toggles[i] = !toggles[i]
+				box.SetTitle(d.title(toggles))
+				items, selected = d.Find(field.GetText(), toggles), 0
+				render()
+				return nil
+			}
+		}
+		switch {
+		case event.Key() == tcell.KeyDown || matchesAny(d.Next, event):
+			move(1)
+		case event.Key() == tcell.KeyUp || matchesAny(d.Prev, event):
+			move(-1)
+		default:
+			return event
+		}
+		return nil
+	})
+
+	box.SetBackgroundColor(cfg.Colors.Bg.Origin())
+	box.SetColumns(-1)
+	box.SetRows(1, -1)
+	box.AddItem(field, 0, 0, 1, 1, 0, 0, true)
+	box.AddItem(list, 1, 0, 1, 1, 0, 0, false)
+	box.SetBorder(true)
+	box.SetTitle(d.title(toggles))
+	box.SetRect(0, 0, d.Width+2, d.Height+3)
+
+	if d.Log != nil {
+		d.Log.DebugF("search box size: %dx%d", d.Width+2, d.Height+3)
+	}
+	return box
+}
+
+func (d Search) title(toggles []bool) string {
+	var enabled []string
+	for i, toggle := range d.Toggles {
+		if toggles[i] {
+			enabled = append(enabled, toggle.Title)
+		}
+	}
+	title := d.Title
+	if len(enabled) > 0 {
+		title += " (" + strings.Join(enabled, ", ") + ")"
+	}
+	if title == "" {
+		return ""
+	}
+	return " " + title + " "
+}
+
+func matchesAny(keys []config.Key, event *tcell.EventKey) bool {
+	for _, key := range keys {
+		if key.Matches(event) {
+			return true
+		}
+	}
+	return false
+}
@@ -63,13 +63,9 @@ func (t *DirTree) Refresh(rootPath string) (err error) {
 		return errors.WithMessage(err, "reading root dir")
 	}
 
-	wd, err := t.fs.Getwd()
-	if err != nil {
-		return errors.WithMessage(err, "getting work dir")
-	}
-
+	// the root path is used instead of the process work dir, since the tree could be shown in an inactive tab
 	t.path = rootPath
-	t.root.Path = t.fs.Join(wd, rootNodeName)
+	t.root.Path = t.fs.Join(rootPath, rootNodeName)
 	t.buildChildren(t.root.TreeNode, rootPath)
 
 	return nil
@@ -86,6 +82,23 @@ func (t *DirTree) ExpandNode(node *tview.TreeNode) {
 	}
 }
 
+// ExpandedPaths returns paths of all expanded nodes, the parent nodes go before their children.
+func (t *DirTree) ExpandedPaths() []string {
+	return t.expandedPaths(t.root.TreeNode)
+}
+
+func (t *DirTree) expandedPaths(target *tview.TreeNode) []string {
+	var paths []string
+	for _, child := range target.GetChildren() {
+		if len(child.GetChildren()) == 0 || !child.IsExpanded() {
+			continue
+		}
+		paths = append(paths, child.GetReference().(*Node).Path)
+		paths = append(paths, t.expandedPaths(child)...)
+	}
+	return paths
+}
+
 func (t DirTree) Path() string {
 	return t.path
 }
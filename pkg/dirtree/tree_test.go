@@ -57,6 +57,10 @@ func TestDirTree(t *testing.T) {
 				assert.Equal("mad.txt", children[1].GetText())
 				assert.Equal("sad", children[2].GetText())
 
+				t.Run("and return paths of the expanded nodes", func(t *testing.T) {
+					assert.Equal([]string{"/wd/target/foo"}, tree.ExpandedPaths())
+				})
+
 				t.Run("and find nested node", func(t *testing.T) {
 					currNode := tree.Find("foo/mad.txt")
 					assert.NotNil(currNode)
@@ -1,5 +1,11 @@
 package events
 
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
 // EventHandler performs some action when an event is dispatched.
 // It must return a new (or the same event).
 // The returned event will be passed to the next subscribers,
@@ -19,7 +25,22 @@ type IEvent interface{}
 
 type Manager interface {
 	Dispatch(IEvent)
-	Subscribe(ISubscriber)
+	// DispatchContext dispatches the event within the context. If the context has been passed to a handler
+	// (see On), then the event is dispatched as a consequence of the handled one.
+	DispatchContext(ctx context.Context, e IEvent)
+	// Subscribe adds the subscribers, they can be removed by the returned subscription.
+	Subscribe(...ISubscriber) Subscription
+	// Wait blocks until all dispatched events are handled.
+	Wait()
+	// Request sends the query to its responders (see Respond) and returns the combined response.
+	Request(ctx context.Context, query IEvent) (interface{}, error)
+}
+
+// Subscription is a handle of subscribers added by Manager.Subscribe.
+type Subscription interface {
+	// Unsubscribe removes the subscribers, so that they don't receive any further events.
+	// It's safe to call it multiple times.
+	Unsubscribe()
 }
 
 type ISubscriber interface {
@@ -35,6 +56,88 @@ func HandleWithPrio(prio float64, fn EventHandler) ISubscriber {
 	return subscriber{handler: fn, prio: prio}
 }
 
+// TypedSubscriber is a subscriber, which handles only events of the specific type.
+// If the type is an interface, then all events implementing it are handled.
+type TypedSubscriber interface {
+	ISubscriber
+	EventType() reflect.Type
+}
+
+// On subscribes the handler function to events of a single type, defined by the function argument.
+// The function must have one of the following signatures, where T is the event type:
+//
+//	func(T)          - the event is passed to the next subscribers as is
+//	func(T) IEvent   - the returned event is passed to the next subscribers (nil stops the propagation)
+//
+// Both signatures can also accept the handling context as the first argument: func(context.Context, T).
+// The events dispatched by the handler within this context (see Manager.DispatchContext) are traced
+// as its consequences, and they never wait for a full queue.
+func On(fn interface{}) ISubscriber {
+	return OnWithPrio(0, fn)
+}
+
+func OnWithPrio(prio float64, fn interface{}) ISubscriber {
+	val := reflect.ValueOf(fn)
+	t := val.Type()
+	withCtx := t.Kind() == reflect.Func && t.NumIn() == 2 && t.In(0) == contextInterface
+	if t.Kind() != reflect.Func || (t.NumIn() != 1 && !withCtx) || t.NumOut() > 1 || (t.NumOut() == 1 && t.Out(0) != eventInterface) {
+		panic(fmt.Sprintf("events: invalid handler %s, expected func([context.Context, ]T) or func([context.Context, ]T) events.IEvent", t))
+	}
+
+	handle := func(ctx context.Context, e IEvent) IEvent {
+		args := []reflect.Value{reflect.ValueOf(e)}
+		if withCtx {
+			args = append([]reflect.Value{reflect.ValueOf(&ctx).Elem()}, args...)
+		}
+		out := val.Call(args)
+		if len(out) == 0 {
+			return e
+		}
+		return out[0].Interface()
+	}
+	handler := func(e IEvent) IEvent {
+		return handle(context.Background(), e)
+	}
+	eventType := t.In(0)
+	if withCtx {
+		eventType = t.In(1)
+	}
+	return typedSubscriber{
+		subscriber: subscriber{handler: handler, prio: prio},
+		eventType:  eventType,
+		name:       funcName(fn),
+		handle:     handle,
+	}
+}
+
+var eventInterface = reflect.TypeOf((*IEvent)(nil)).Elem()
+
+type typedSubscriber struct {
+	subscriber
+	eventType reflect.Type
+	// name of the original handler function, which is wrapped by the subscriber
+	name string
+	// the handler, which receives the handling context
+	handle func(ctx context.Context, e IEvent) IEvent
+}
+
+func (s typedSubscriber) EventType() reflect.Type {
+	return s.eventType
+}
+
+func (s typedSubscriber) Name() string {
+	return s.name
+}
+
+func (s typedSubscriber) handleContext(ctx context.Context, e IEvent) IEvent {
+	return s.handle(ctx, e)
+}
+
+// contextSubscriber is a subscriber, which receives the handling context.
+type contextSubscriber interface {
+	handleContext(ctx context.Context, e IEvent) IEvent
+}
+
 type subscriber struct {
 	handler EventHandler
 	prio    float64
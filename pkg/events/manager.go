@@ -1,78 +1,433 @@
 package events
 
 import (
+	"context"
+	"reflect"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const defaultQueueSize = 1024
+
 type ManagerConfig struct {
 	// DelayedStart defines whether events will be dispatched immediately,
 	// of buffered till Manager.Start() is called.
 	// It allows to delay starting the event manager without loosing any events.
 	DelayedStart bool
+	// Queued defines whether events are handled asynchronously by a single dispatch loop.
+	// Events are handled one by one in the order they have been dispatched.
+	// An event dispatched by a handler is queued after the current one, instead of being handled recursively.
+	Queued bool
+	// QueueSize limits number of events waiting in the queue (1024 by default).
+	// DispatchContext blocks while the queue is full, unless its context has been passed to a handler.
+	// Dispatch never blocks, since it's called by the handlers and by the goroutine running them (e.g. the UI one).
+	QueueSize int
+	// Executor runs handlers of a queued event, e.g. it can marshal them to the UI goroutine.
+	// It must not return until the function is done.
+	// By default the handlers are called directly by the dispatch loop.
+	Executor func(fn func())
+	// MaxFailures is the number of panics, after which a subscriber is disabled (3 by default).
+	// Negative value never disables subscribers.
+	MaxFailures int
+	// RequestTimeout limits waiting for responses, if the request context has no deadline (2s by default).
+	RequestTimeout time.Duration
 }
 
 type DefaultManager struct {
 	cfg ManagerConfig
-	sub []ISubscriber
+	sub []*subscription
 	mu  *sync.Mutex
+	// subscribers of every dispatched event type, it's reset on every subscribe
+	chains  map[reflect.Type][]*subscription
+	lastSeq int
 
 	filter func(IEvent) bool
 
 	// support for delayed start
-	buffer  []IEvent
+	buffer  []dispatched
 	started bool
+
+	// support for queued mode
+	queue []dispatched
+	cond  *sync.Cond
+	// whether the dispatch loop is handling an event at the moment
+	busy   bool
+	closed bool
+
+	// support for tracing
+	tracer      Tracer
+	lastEventID uint64
+	// all event types known by typed subscriptions
+	types map[string]reflect.Type
+}
+
+// dispatched is an event waiting to be handled
+type dispatched struct {
+	event  IEvent
+	id     uint64
+	parent uint64
+	// the isolated scope, which has dispatched the event
+	domain *Scope
+}
+
+// handlingKey is the key of the handling context value, which is passed to the handlers (see On).
+type handlingKey struct{}
+
+// handling describes the event, which is being handled.
+type handling struct {
+	id uint64
+}
+
+// handlingOf returns the handled event, whose handler has received the context.
+func handlingOf(ctx context.Context) (handling, bool) {
+	if ctx == nil {
+		return handling{}, false
+	}
+	h, ok := ctx.Value(handlingKey{}).(handling)
+	return h, ok
 }
 
 func NewManager(cfg ManagerConfig) (*DefaultManager, error) {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.MaxFailures == 0 {
+		cfg.MaxFailures = defaultMaxFailures
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = defaultRequestTimeout
+	}
+	if cfg.Executor == nil {
+		cfg.Executor = func(fn func()) { fn() }
+	}
+
+	mu := &sync.Mutex{}
 	em := &DefaultManager{
 		cfg:     cfg,
-		mu:      &sync.Mutex{},
+		mu:      mu,
+		cond:    sync.NewCond(mu),
+		chains:  make(map[reflect.Type][]*subscription),
+		types:   make(map[string]reflect.Type),
 		filter:  func(IEvent) bool { return true },
 		started: !cfg.DelayedStart,
 	}
+	if em.started && cfg.Queued {
+		go em.loop()
+	}
 
 	return em, nil
 }
 
 func (em *DefaultManager) Dispatch(e IEvent) {
+	em.dispatchIn(nil, e, nil)
+}
+
+func (em *DefaultManager) DispatchContext(ctx context.Context, e IEvent) {
+	em.dispatchIn(ctx, e, nil)
+}
+
+// dispatchIn dispatches the event within the context, nil context never waits for the queue.
+func (em *DefaultManager) dispatchIn(ctx context.Context, e IEvent, domain *Scope) {
+	d := em.track(ctx, e)
+	d.domain = domain
+
+	em.mu.Lock()
 	if !em.started {
-		em.mu.Lock()
-		em.buffer = append(em.buffer, e)
+		em.buffer = append(em.buffer, d)
 		em.mu.Unlock()
 		return
 	}
+	if em.cfg.Queued {
+		em.enqueue(ctx, d)
+		em.mu.Unlock()
+		return
+	}
+	em.mu.Unlock()
+
+	em.handle(d)
+}
+
+func (em *DefaultManager) Subscribe(subscribers ...ISubscriber) Subscription {
+	return em.subscribeIn(nil, subscribers...)
+}
 
+func (em *DefaultManager) subscribeIn(domain *Scope, subscribers ...ISubscriber) Subscription {
 	em.mu.Lock()
-	subscribers := em.sub
-	em.mu.Unlock()
+	defer em.mu.Unlock()
 
+	handle := &subscriptionHandle{em: em}
 	for _, sub := range subscribers {
-		e = sub.Handler()(e)
-		if e == nil {
-			return
+		em.lastSeq++
+		s := &subscription{ISubscriber: sub, seq: em.lastSeq, name: subscriberName(sub), domain: domain}
+		if typed, ok := sub.(TypedSubscriber); ok {
+			s.eventType = typed.EventType()
+			s.responder, _ = sub.(Responder)
+			if s.eventType.Kind() != reflect.Interface && s.responder == nil {
+				em.types[TypeName(s.eventType)] = s.eventType
+			}
 		}
+		em.sub = append(em.sub, s)
+		handle.subs = append(handle.subs, s)
 	}
+	sort.SliceStable(em.sub, func(i, j int) bool {
+		return em.sub[i].Priority() > em.sub[j].Priority()
+	})
+	em.chains = make(map[reflect.Type][]*subscription)
+	return handle
 }
 
-func (em *DefaultManager) Subscribe(subscriber ISubscriber) {
+func (em *DefaultManager) unsubscribe(subs []*subscription) {
 	em.mu.Lock()
 	defer em.mu.Unlock()
 
-	em.sub = append(em.sub, subscriber)
-	sort.SliceStable(em.sub, func(i, j int) bool {
-		return em.sub[i].Priority() > em.sub[j].Priority()
-	})
+	removed := make(map[*subscription]bool, len(subs))
+	for _, sub := range subs {
+		// the subscription could be in a chain of an event, which is being handled at the moment
+		atomic.StoreInt32(&sub.removed, 1)
+		removed[sub] = true
+	}
+
+	remaining := make([]*subscription, 0, len(em.sub))
+	for _, sub := range em.sub {
+		if !removed[sub] {
+			remaining = append(remaining, sub)
+		}
+	}
+	em.sub = remaining
+	em.chains = make(map[reflect.Type][]*subscription)
+}
+
+// Wait blocks until all queued events (including the ones dispatched by their handlers) are handled.
+// It returns immediately in synchronous mode. It must not be called from a handler.
+func (em *DefaultManager) Wait() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	for (len(em.queue) > 0 || em.busy) && !em.closed {
+		em.cond.Wait()
+	}
 }
 
 func (em *DefaultManager) Init() error {
+	em.mu.Lock()
 	em.started = true
-	for _, event := range em.buffer {
-		em.Dispatch(event)
+	buffer := em.buffer
+	em.buffer = nil
+	if em.cfg.Queued {
+		// the buffered events are queued regardless of the queue size,
+		// since the handlers might be unable to run until the caller has finished the start
+		em.queue = append(em.queue, buffer...)
+		em.mu.Unlock()
+		go em.loop()
+		return nil
+	}
+	em.mu.Unlock()
+
+	for _, d := range buffer {
+		em.handle(d)
 	}
 	return nil
 }
 
+// Close stops the dispatch loop, the queued events are dropped.
 func (em *DefaultManager) Close() error {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	em.closed = true
+	em.queue = nil
+	em.cond.Broadcast()
 	return nil
 }
+
+func (em *DefaultManager) handle(d dispatched) {
+	e := d.event
+	eventType := reflect.TypeOf(e)
+	chain := em.chain(eventType)
+
+	// the handlers receive the context, which makes the events dispatched within it the consequences of this one
+	ctx := context.WithValue(context.Background(), handlingKey{}, handling{id: d.id})
+
+	var trace *Trace
+	if tracer := em.getTracer(); tracer != nil {
+		trace = &Trace{ID: d.id, Parent: d.parent, Time: time.Now(), Event: e}
+		defer func() {
+			trace.Duration = time.Since(trace.Time)
+			tracer.Trace(*trace)
+		}()
+	}
+
+	for i := 0; i < len(chain); i++ {
+		sub := chain[i]
+		if atomic.LoadInt32(&sub.removed) == 1 || !sub.reaches(d.domain) {
+			continue
+		}
+
+		var start time.Time
+		if trace != nil {
+			start = time.Now()
+		}
+		result, failed := em.call(ctx, sub, e)
+		if trace != nil {
+			trace.Handlers = append(trace.Handlers, HandlerTrace{
+				Name:     sub.name,
+				Priority: sub.Priority(),
+				Duration: time.Since(start),
+				Changed:  result != nil && changed(e, result),
+				Stopped:  result == nil,
+				Failed:   failed,
+			})
+		}
+
+		if e = result; e == nil {
+			return
+		}
+
+		if t := reflect.TypeOf(e); t != eventType {
+			// the event has been replaced by an event of another type,
+			// so it's passed to the subscribers of the new type, which are next to the current one
+			eventType, chain = t, em.chain(t)
+			i = sort.Search(len(chain), func(j int) bool { return chain[j].after(sub) }) - 1
+		}
+	}
+}
+
+// chain returns all subscribers of the event type in order they must be called.
+func (em *DefaultManager) chain(eventType reflect.Type) []*subscription {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	if chain, ok := em.chains[eventType]; ok {
+		return chain
+	}
+	var chain []*subscription
+	for _, sub := range em.sub {
+		if sub.responder == nil && sub.handles(eventType) {
+			chain = append(chain, sub)
+		}
+	}
+	em.chains[eventType] = chain
+	return chain
+}
+
+// enqueue adds the event to the queue, the lock must be held by the caller.
+// While the queue is full, it waits until the context is done, then the event is dropped.
+func (em *DefaultManager) enqueue(ctx context.Context, d dispatched) {
+	// a handler must never wait for the queue, which is processed by its own goroutine
+	if _, handled := handlingOf(ctx); ctx != nil && !handled {
+		em.waitForRoom(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+	if em.closed {
+		return
+	}
+	em.queue = append(em.queue, d)
+	em.cond.Broadcast()
+}
+
+// waitForRoom blocks while the queue is full, until the context is done. The lock must be held by the caller.
+func (em *DefaultManager) waitForRoom(ctx context.Context) {
+	if len(em.queue) < em.cfg.QueueSize {
+		return
+	}
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				em.mu.Lock()
+				em.cond.Broadcast()
+				em.mu.Unlock()
+			case <-stop:
+			}
+		}()
+	}
+	for len(em.queue) >= em.cfg.QueueSize && !em.closed && ctx.Err() == nil {
+		em.cond.Wait()
+	}
+}
+
+func (em *DefaultManager) loop() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	for {
+		for len(em.queue) == 0 && !em.closed {
+			em.cond.Wait()
+		}
+		if em.closed {
+			return
+		}
+
+		d := em.queue[0]
+		em.queue[0] = dispatched{}
+		em.queue = em.queue[1:]
+		em.busy = true
+		em.cond.Broadcast()
+		em.mu.Unlock()
+
+		em.cfg.Executor(func() {
+			em.handle(d)
+		})
+
+		em.mu.Lock()
+		em.busy = false
+		em.cond.Broadcast()
+	}
+}
+
+type subscription struct {
+	ISubscriber
+	// order of subscribing
+	seq int
+	// nil if the subscriber handles all events
+	eventType reflect.Type
+	removed   int32
+	name      string
+	// number of panics of the subscriber
+	failures int32
+	// nil if the subscriber handles events instead of queries
+	responder Responder
+	// the isolated scope of the subscriber, nil if it's global
+	domain *Scope
+}
+
+// reaches tells whether the subscriber receives events dispatched in the domain,
+// i.e. whether one of the domains contains the other one.
+func (s *subscription) reaches(domain *Scope) bool {
+	return s.domain == nil || domain == nil || s.domain.contains(domain) || domain.contains(s.domain)
+}
+
+func (s *subscription) handles(eventType reflect.Type) bool {
+	switch {
+	case s.eventType == nil:
+		return true
+	case s.eventType.Kind() == reflect.Interface:
+		return eventType != nil && eventType.Implements(s.eventType)
+	default:
+		return s.eventType == eventType
+	}
+}
+
+// after tells whether the subscription is called after the other one.
+func (s *subscription) after(other *subscription) bool {
+	if s.Priority() != other.Priority() {
+		return s.Priority() < other.Priority()
+	}
+	return s.seq > other.seq
+}
+
+type subscriptionHandle struct {
+	em   *DefaultManager
+	subs []*subscription
+	once sync.Once
+}
+
+func (h *subscriptionHandle) Unsubscribe() {
+	h.once.Do(func() { h.em.unsubscribe(h.subs) })
+}
@@ -1,9 +1,11 @@
 package events
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 const (
@@ -194,6 +196,383 @@ func TestDelayedStart(t *testing.T) {
 	})
 }
 
+type Fish struct {
+	Name string
+}
+
+type Bird struct {
+	Name string
+}
+
+func (b Bird) Fly() string {
+	return b.Name + " flies"
+}
+
+type Flyer interface {
+	Fly() string
+}
+
+func TestTypedSubscriptions(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should call handlers only for events of their type", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		mng.Subscribe(
+			On(func(e Fish) { handled = append(handled, "fish: "+e.Name) }),
+			On(func(e Bird) { handled = append(handled, "bird: "+e.Name) }),
+		)
+
+		mng.Dispatch(Bird{Name: "eagle"})
+		mng.Dispatch(Fish{Name: "tuna"})
+		mng.Dispatch(Event{Id: EventBird, Payload: "owl"})
+
+		assert.Equal([]string{"bird: eagle", "fish: tuna"}, handled)
+	})
+
+	t.Run("should consider priority among typed and generic subscribers", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		mng.Subscribe(On(func(e Bird) { handled = append(handled, "bob") }))
+		mng.Subscribe(HandleWithPrio(10, func(e IEvent) IEvent {
+			handled = append(handled, "eric")
+			return e
+		}))
+		mng.Subscribe(OnWithPrio(100, func(e Bird) { handled = append(handled, "john") }))
+
+		mng.Dispatch(Bird{Name: "eagle"})
+
+		assert.Equal([]string{"john", "eric", "bob"}, handled)
+	})
+
+	t.Run("should pass the rewritten event to the next subscribers", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		mng.Subscribe(
+			OnWithPrio(10, func(e Bird) IEvent { return Bird{Name: "big " + e.Name} }),
+			OnWithPrio(5, func(e Bird) IEvent { return Fish{Name: "flying " + e.Name} }),
+			OnWithPrio(20, func(e Fish) { handled = append(handled, "too early: "+e.Name) }),
+			On(func(e Fish) { handled = append(handled, "fish: "+e.Name) }),
+			On(func(e Bird) { handled = append(handled, "bird: "+e.Name) }),
+		)
+
+		mng.Dispatch(Bird{Name: "eagle"})
+
+		assert.Equal([]string{"fish: flying big eagle"}, handled)
+	})
+
+	t.Run("should stop propagation", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		mng.Subscribe(
+			OnWithPrio(10, func(e Bird) IEvent { return StopPropagation }),
+			On(func(e Bird) { handled = append(handled, "bird: "+e.Name) }),
+		)
+
+		mng.Dispatch(Bird{Name: "eagle"})
+
+		assert.Len(handled, 0)
+	})
+
+	t.Run("should handle events implementing the interface", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		mng.Subscribe(On(func(e Flyer) { handled = append(handled, e.Fly()) }))
+
+		mng.Dispatch(Fish{Name: "tuna"})
+		mng.Dispatch(Bird{Name: "eagle"})
+
+		assert.Equal([]string{"eagle flies"}, handled)
+	})
+
+	t.Run("should panic if the handler has invalid signature", func(t *testing.T) {
+		assert.Panics(func() { On(func(e Bird) bool { return true }) })
+		assert.Panics(func() { On(func(a, b Bird) {}) })
+		assert.Panics(func() { On("foo") })
+	})
+}
+
+func TestUnsubscribe(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should not call removed subscribers", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		sub := mng.Subscribe(
+			On(func(e Bird) { handled = append(handled, "bob") }),
+			On(func(e Bird) { handled = append(handled, "john") }),
+		)
+		mng.Subscribe(On(func(e Bird) { handled = append(handled, "eric") }))
+
+		mng.Dispatch(Bird{Name: "eagle"})
+		sub.Unsubscribe()
+		sub.Unsubscribe()
+		mng.Dispatch(Bird{Name: "owl"})
+
+		assert.Equal([]string{"bob", "john", "eric", "eric"}, handled)
+	})
+
+	t.Run("should not call subscribers removed while the event is being handled", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		var sub Subscription
+		mng.Subscribe(OnWithPrio(10, func(e Bird) {
+			handled = append(handled, "bob")
+			sub.Unsubscribe()
+		}))
+		sub = mng.Subscribe(On(func(e Bird) { handled = append(handled, "john") }))
+
+		mng.Dispatch(Bird{Name: "eagle"})
+
+		assert.Equal([]string{"bob"}, handled)
+	})
+}
+
+func TestPanicIsolation(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should pass the event to the next subscribers, if a subscriber panics", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		var failures []EventSubscriberFailed
+		mng.Subscribe(
+			OnWithPrio(10, func(e Bird) { panic("boom") }),
+			On(func(e Bird) { handled = append(handled, e.Name) }),
+			On(func(e EventSubscriberFailed) { failures = append(failures, e) }),
+		)
+
+		mng.Dispatch(Bird{Name: "eagle"})
+
+		assert.Equal([]string{"eagle"}, handled)
+		assert.Len(failures, 1)
+		assert.Equal(Bird{Name: "eagle"}, failures[0].Event)
+		assert.Equal("boom", failures[0].Panic)
+		assert.Equal(1, failures[0].Failures)
+		assert.False(failures[0].Disabled)
+		assert.Contains(failures[0].Subscriber, "TestPanicIsolation")
+		assert.NotEmpty(failures[0].Stack)
+	})
+
+	t.Run("should disable a subscriber after max failures", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{MaxFailures: 2})
+		assert.NoError(err)
+
+		calls := 0
+		var failures []EventSubscriberFailed
+		mng.Subscribe(
+			On(func(e Bird) {
+				calls++
+				panic("boom")
+			}),
+			On(func(e EventSubscriberFailed) { failures = append(failures, e) }),
+		)
+
+		mng.Dispatch(Bird{Name: "eagle"})
+		mng.Dispatch(Bird{Name: "owl"})
+		mng.Dispatch(Bird{Name: "crow"})
+
+		assert.Equal(2, calls)
+		assert.Len(failures, 2)
+		assert.False(failures[0].Disabled)
+		assert.True(failures[1].Disabled)
+	})
+
+	t.Run("should not report failures of failure subscribers", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{MaxFailures: -1})
+		assert.NoError(err)
+
+		calls := 0
+		mng.Subscribe(
+			On(func(e Bird) { panic("boom") }),
+			On(func(e EventSubscriberFailed) {
+				calls++
+				panic("boom again")
+			}),
+		)
+
+		mng.Dispatch(Bird{Name: "eagle"})
+
+		assert.Equal(1, calls)
+	})
+}
+
+func TestQueued(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should handle events asynchronously in the order they are dispatched", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{Queued: true})
+		assert.NoError(err)
+		defer mng.Close()
+
+		handled := &handler{}
+		mng.Subscribe(handled.withName(EventBird, "bob"))
+		mng.Subscribe(handled.withName(EventFish, "eric"))
+
+		mng.Dispatch(Event{Id: EventBird, Payload: "eagle"})
+		mng.Dispatch(Event{Id: EventFish, Payload: "tuna"})
+		mng.Dispatch(Event{Id: EventBird, Payload: "owl"})
+		mng.Wait()
+
+		assert.Equal([]string{
+			"'bob' handled 'bird/eagle'",
+			"'eric' handled 'fish/tuna'",
+			"'bob' handled 'bird/owl'",
+		}, handled.events)
+	})
+
+	t.Run("should handle events dispatched by a handler after the current event", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{Queued: true, QueueSize: 1})
+		assert.NoError(err)
+		defer mng.Close()
+
+		handled := &handler{}
+		mng.Subscribe(On(func(ctx context.Context, event Event) {
+			if event.Id == EventBird {
+				// the queue is already full, but a handler should not be blocked by it
+				mng.DispatchContext(ctx, Event{Id: EventFish, Payload: "first"})
+				mng.DispatchContext(ctx, Event{Id: EventFish, Payload: "second"})
+			}
+		}))
+		mng.Subscribe(handled.withName(EventBird, "bob"))
+		mng.Subscribe(handled.withName(EventFish, "eric"))
+
+		mng.Dispatch(Event{Id: EventBird, Payload: "eagle"})
+		mng.Wait()
+
+		assert.Equal([]string{
+			"'bob' handled 'bird/eagle'",
+			"'eric' handled 'fish/first'",
+			"'eric' handled 'fish/second'",
+		}, handled.events)
+	})
+
+	t.Run("should block dispatching while the queue is full", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{Queued: true, QueueSize: 1})
+		assert.NoError(err)
+		defer mng.Close()
+
+		release := make(chan struct{})
+		mng.Subscribe(HandleFunc(func(e IEvent) IEvent {
+			<-release
+			return e
+		}))
+
+		dispatched := make(chan struct{})
+		go func() {
+			ctx := context.Background()
+			mng.DispatchContext(ctx, Event{Id: EventBird, Payload: "handled"})
+			mng.DispatchContext(ctx, Event{Id: EventBird, Payload: "queued"})
+			mng.DispatchContext(ctx, Event{Id: EventBird, Payload: "blocked"})
+			close(dispatched)
+		}()
+
+		select {
+		case <-dispatched:
+			assert.Fail("dispatching should be blocked")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		<-dispatched
+		mng.Wait()
+	})
+
+	t.Run("should drop the event when the context is done while the queue is full", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{Queued: true, QueueSize: 1})
+		assert.NoError(err)
+		defer mng.Close()
+
+		release := make(chan struct{})
+		handled := &handler{}
+		mng.Subscribe(HandleFunc(func(e IEvent) IEvent {
+			<-release
+			return e
+		}))
+		mng.Subscribe(handled.withName(EventBird, "bob"))
+
+		mng.Dispatch(Event{Id: EventBird, Payload: "eagle"})
+		mng.Dispatch(Event{Id: EventBird, Payload: "owl"})
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		mng.DispatchContext(ctx, Event{Id: EventBird, Payload: "dropped"})
+
+		close(release)
+		mng.Wait()
+		assert.Len(handled.events, 2)
+	})
+
+	t.Run("should not block dispatching without context", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{Queued: true, QueueSize: 1})
+		assert.NoError(err)
+		defer mng.Close()
+
+		release := make(chan struct{})
+		handled := &handler{}
+		mng.Subscribe(HandleFunc(func(e IEvent) IEvent {
+			<-release
+			return e
+		}))
+		mng.Subscribe(handled.withName(EventBird, "bob"))
+
+		mng.Dispatch(Event{Id: EventBird, Payload: "eagle"})
+		mng.Dispatch(Event{Id: EventBird, Payload: "owl"})
+		mng.Dispatch(Event{Id: EventBird, Payload: "hawk"})
+
+		close(release)
+		mng.Wait()
+		assert.Len(handled.events, 3)
+	})
+
+	t.Run("should run handlers by the executor", func(t *testing.T) {
+		executed := 0
+		mng, err := NewManager(ManagerConfig{Queued: true, Executor: func(fn func()) {
+			executed++
+			fn()
+		}})
+		assert.NoError(err)
+		defer mng.Close()
+
+		handled := &handler{}
+		mng.Subscribe(handled.withName(EventBird, "bob"))
+		mng.Dispatch(Event{Id: EventBird, Payload: "eagle"})
+		mng.Wait()
+
+		assert.Equal(1, executed)
+		assert.Len(handled.events, 1)
+	})
+
+	t.Run("should queue events buffered before delayed start", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{Queued: true, QueueSize: 1, DelayedStart: true})
+		assert.NoError(err)
+		defer mng.Close()
+
+		handled := &handler{}
+		mng.Subscribe(handled.withName(EventBird, "bob"))
+		mng.Dispatch(Event{Id: EventBird, Payload: "eagle"})
+		mng.Dispatch(Event{Id: EventBird, Payload: "owl"})
+
+		assert.NoError(mng.Init())
+		mng.Wait()
+		assert.Len(handled.events, 2)
+	})
+}
+
 type handler struct {
 	events []string
 }
@@ -0,0 +1,248 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxEncodeDepth limits encoding of nested values of events
+const maxEncodeDepth = 5
+
+// maxRecordSize is the max length of a single recorded line
+const maxRecordSize = 16 * 1024 * 1024
+
+type record struct {
+	ID       uint64          `json:"id"`
+	Parent   uint64          `json:"parent,omitempty"`
+	Time     time.Time       `json:"time"`
+	Type     string          `json:"type"`
+	Event    interface{}     `json:"event"`
+	Duration int64           `json:"duration_us"`
+	Handlers []handlerRecord `json:"handlers"`
+	// Redacted events are recorded without their fields
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+type handlerRecord struct {
+	Name     string  `json:"name"`
+	Priority float64 `json:"priority"`
+	Duration int64   `json:"duration_us"`
+	Changed  bool    `json:"changed,omitempty"`
+	Stopped  bool    `json:"stopped,omitempty"`
+	Failed   bool    `json:"failed,omitempty"`
+}
+
+// Recorder is a Tracer, which writes every trace as a JSON line, so that the session could be replayed later.
+// Fields of events, which can not be encoded (e.g. functions or views), are omitted.
+type Recorder struct {
+	mu       *sync.Mutex
+	enc      *json.Encoder
+	err      error
+	redacted map[reflect.Type]bool
+}
+
+// NewRecorder creates a recorder, which writes to the writer. The redacted events are recorded
+// without their fields (e.g. the ones containing passwords), so they are not replayed.
+func NewRecorder(w io.Writer, redacted ...IEvent) *Recorder {
+	r := &Recorder{mu: &sync.Mutex{}, enc: json.NewEncoder(w), redacted: make(map[reflect.Type]bool)}
+	for _, event := range redacted {
+		r.redacted[reflect.TypeOf(event)] = true
+	}
+	return r
+}
+
+func (r *Recorder) Trace(trace Trace) {
+	rec := record{
+		ID:       trace.ID,
+		Parent:   trace.Parent,
+		Time:     trace.Time,
+		Duration: int64(trace.Duration / time.Microsecond),
+		Handlers: make([]handlerRecord, 0, len(trace.Handlers)),
+	}
+	if t := reflect.TypeOf(trace.Event); t != nil {
+		rec.Type = TypeName(t)
+	}
+	if r.redacted[reflect.TypeOf(trace.Event)] {
+		rec.Redacted = true
+	} else {
+		rec.Event = encodeEvent(trace.Event)
+	}
+	for _, h := range trace.Handlers {
+		rec.Handlers = append(rec.Handlers, handlerRecord{
+			Name:     h.Name,
+			Priority: h.Priority,
+			Duration: int64(h.Duration / time.Microsecond),
+			Changed:  h.Changed,
+			Stopped:  h.Stopped,
+			Failed:   h.Failed,
+		})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.enc.Encode(rec); err != nil && r.err == nil {
+		r.err = errors.WithMessage(err, "record event")
+	}
+}
+
+// Err returns the first error occurred while writing the traces.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+type ReplayOptions struct {
+	// Speed divides the recorded intervals between events, zero value dispatches the events without delays.
+	Speed float64
+}
+
+// Replay dispatches the recorded events to the manager and returns number of the dispatched events.
+// Only the events dispatched outside of handlers are replayed, the rest are dispatched by the handlers again.
+// Redacted events are not replayed, since their fields are unknown.
+// Events of unknown types (which are not handled by any typed subscriber) and events,
+// which could not be decoded (e.g. recorded by another version of the app), are ignored.
+func Replay(em *DefaultManager, source io.Reader, opts ReplayOptions) (int, error) {
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(nil, maxRecordSize)
+
+	dispatched := 0
+	var prevTime time.Time
+	for line := 1; scanner.Scan(); line++ {
+		var rec struct {
+			Parent   uint64          `json:"parent"`
+			Time     time.Time       `json:"time"`
+			Type     string          `json:"type"`
+			Event    json.RawMessage `json:"event"`
+			Redacted bool            `json:"redacted"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return dispatched, errors.WithMessagef(err, "read recorded event at line %d", line)
+		}
+		if rec.Parent != 0 || rec.Redacted {
+			continue
+		}
+		eventType, ok := em.EventType(rec.Type)
+		if !ok {
+			continue
+		}
+
+		event := reflect.New(eventType)
+		if len(rec.Event) > 0 && string(rec.Event) != "null" {
+			if err := json.Unmarshal(rec.Event, event.Interface()); err != nil {
+				continue
+			}
+		}
+
+		if opts.Speed > 0 && !prevTime.IsZero() && rec.Time.After(prevTime) {
+			time.Sleep(time.Duration(float64(rec.Time.Sub(prevTime)) / opts.Speed))
+		}
+		prevTime = rec.Time
+
+		em.Dispatch(event.Elem().Interface())
+		dispatched++
+	}
+	return dispatched, errors.WithMessage(scanner.Err(), "read recorded events")
+}
+
+var (
+	jsonMarshaler   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+func encodeEvent(e IEvent) interface{} {
+	if e == nil {
+		return nil
+	}
+	val, _ := encodeValue(reflect.ValueOf(e), 0)
+	return val
+}
+
+// encodeValue converts the value to a structure, which can be encoded to JSON.
+// It returns false, if the value can not be encoded.
+func encodeValue(v reflect.Value, depth int) (interface{}, bool) {
+	if depth > maxEncodeDepth || !v.IsValid() || !v.CanInterface() {
+		return nil, false
+	}
+	if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+		if v.Type().Implements(jsonMarshaler) {
+			return v.Interface(), true
+		}
+		if reflect.PtrTo(v.Type()).Implements(jsonUnmarshaler) {
+			// the value has a custom format, so it could not be decoded, if it's encoded as is
+			return nil, false
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v.Interface(), true
+
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			return v.Bytes(), true
+		}
+		items := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, ok := encodeValue(v.Index(i), depth+1)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, item)
+		}
+		return items, true
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		items := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			if item, ok := encodeValue(v.MapIndex(key), depth+1); ok {
+				items[key.String()] = item
+			}
+		}
+		return items, true
+
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			name := fieldName(v.Type().Field(i))
+			if name == "" {
+				continue
+			}
+			if field, ok := encodeValue(v.Field(i), depth+1); ok {
+				fields[name] = field
+			}
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// fieldName returns the JSON name of the struct field, or an empty string if the field must be skipped.
+func fieldName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return "" // unexported
+	}
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return tag
+	}
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+type Nest struct {
+	Birds []Bird
+	Hatch func()
+}
+
+func TestRecorder(t *testing.T) {
+	assert := require.New(t)
+
+	subscribe := func(mng *DefaultManager, handled *[]string) {
+		mng.Subscribe(
+			OnWithPrio(10, func(e Bird) IEvent { return Bird{Name: "big " + e.Name} }),
+			On(func(ctx context.Context, e Bird) {
+				*handled = append(*handled, "bird: "+e.Name)
+				mng.DispatchContext(ctx, Fish{Name: "tuna"})
+			}),
+			On(func(e Fish) IEvent {
+				*handled = append(*handled, "fish: "+e.Name)
+				return StopPropagation
+			}),
+			On(func(e Nest) { *handled = append(*handled, "nest") }),
+		)
+	}
+
+	var session bytes.Buffer
+	t.Run("should record traces of dispatched events", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+		var handled []string
+		subscribe(mng, &handled)
+
+		recorder := NewRecorder(&session)
+		mng.SetTracer(recorder)
+		mng.Dispatch(Bird{Name: "eagle"})
+		mng.Dispatch(Nest{Birds: []Bird{{Name: "owl"}}, Hatch: func() {}})
+		mng.SetTracer(nil)
+		mng.Dispatch(Bird{Name: "not recorded"})
+
+		assert.NoError(recorder.Err())
+		lines := strings.Split(strings.TrimSpace(session.String()), "\n")
+		assert.Len(lines, 3)
+
+		var records []record
+		for _, line := range lines {
+			var rec record
+			assert.NoError(json.Unmarshal([]byte(line), &rec))
+			records = append(records, rec)
+		}
+
+		// nested events are finished before the parent one
+		fish, bird, nest := records[0], records[1], records[2]
+		assert.Equal("github.com/jumale/gooster/pkg/events.Bird", bird.Type)
+		assert.Equal(map[string]interface{}{"Name": "eagle"}, bird.Event)
+		assert.Equal(uint64(0), bird.Parent)
+		assert.Len(bird.Handlers, 2)
+		assert.True(bird.Handlers[0].Changed)
+		assert.Equal(float64(10), bird.Handlers[0].Priority)
+		assert.Contains(bird.Handlers[0].Name, "events.TestRecorder.")
+		assert.False(bird.Handlers[1].Changed)
+
+		assert.Equal(bird.ID, fish.Parent)
+		assert.True(fish.Handlers[0].Stopped)
+
+		assert.Equal(map[string]interface{}{
+			"Birds": []interface{}{map[string]interface{}{"Name": "owl"}},
+		}, nest.Event, "should skip fields which can not be encoded")
+	})
+
+	t.Run("should replay the recorded session", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+		var handled []string
+		subscribe(mng, &handled)
+
+		dispatched, err := Replay(mng, bytes.NewReader(session.Bytes()), ReplayOptions{})
+		assert.NoError(err)
+		assert.Equal(2, dispatched)
+		assert.Equal([]string{"bird: big eagle", "fish: tuna", "nest"}, handled)
+	})
+
+	t.Run("should record redacted events without fields and not replay them", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+		var handled []string
+		subscribe(mng, &handled)
+
+		var redacted bytes.Buffer
+		mng.SetTracer(NewRecorder(&redacted, Fish{}))
+		mng.Dispatch(Fish{Name: "secret"})
+		mng.SetTracer(nil)
+
+		var rec record
+		assert.NoError(json.Unmarshal(redacted.Bytes(), &rec))
+		assert.True(rec.Redacted)
+		assert.Nil(rec.Event)
+		assert.NotContains(redacted.String(), "secret")
+
+		dispatched, err := Replay(mng, bytes.NewReader(redacted.Bytes()), ReplayOptions{})
+		assert.NoError(err)
+		assert.Equal(0, dispatched)
+	})
+}
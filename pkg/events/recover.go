@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+const defaultMaxFailures = 3
+
+// EventSubscriberFailed is dispatched when a subscriber panics while handling an event.
+// The event is passed to the next subscribers as if the failed one has not been called.
+type EventSubscriberFailed struct {
+	Subscriber string
+	Event      IEvent
+	Panic      interface{}
+	Stack      string
+	// Failures is the total number of panics of the subscriber.
+	Failures int
+	// Disabled tells whether the subscriber has been removed because of too many failures.
+	Disabled bool
+}
+
+func (e EventSubscriberFailed) Error() string {
+	return fmt.Sprintf("subscriber %s panicked while handling %T: %v", e.Subscriber, e.Event, e.Panic)
+}
+
+// call runs the subscriber and recovers it, if it panics.
+// It returns the original event and true, if the subscriber has failed.
+func (em *DefaultManager) call(ctx context.Context, sub *subscription, e IEvent) (result IEvent, failed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, failed = e, true
+			em.fail(ctx, sub, e, r, string(debug.Stack()))
+		}
+	}()
+	if handler, ok := sub.ISubscriber.(contextSubscriber); ok {
+		return handler.handleContext(ctx, e), false
+	}
+	return sub.Handler()(e), false
+}
+
+// fail disables the subscriber, if it has failed too many times, and notifies other subscribers about the failure.
+func (em *DefaultManager) fail(ctx context.Context, sub *subscription, e IEvent, panicValue interface{}, stack string) {
+	failures := int(atomic.AddInt32(&sub.failures, 1))
+	disabled := em.cfg.MaxFailures > 0 && failures >= em.cfg.MaxFailures
+	if disabled {
+		em.unsubscribe([]*subscription{sub})
+	}
+
+	// a failure of a failure handler is not reported, otherwise it could fail endlessly
+	if _, ok := e.(EventSubscriberFailed); ok {
+		return
+	}
+	em.dispatchIn(ctx, EventSubscriberFailed{
+		Subscriber: sub.name,
+		Event:      e,
+		Panic:      panicValue,
+		Stack:      stack,
+		Failures:   failures,
+		Disabled:   disabled,
+	}, nil)
+}
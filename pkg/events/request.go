@@ -0,0 +1,275 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"reflect"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+const defaultRequestTimeout = 2 * time.Second
+
+// Strategy defines how responses of multiple responders are combined into a single result.
+type Strategy int
+
+const (
+	// First returns the first non-zero response in order of the responder priorities.
+	// It doesn't wait for the responders with lower priorities, when the response is known.
+	First Strategy = iota
+	// All returns all non-zero responses as []interface{} in order of the responder priorities.
+	All
+	// Merge combines all non-zero responses into one: slices are concatenated, maps are united
+	// and values implementing Merger are merged by themselves.
+	Merge
+)
+
+// AggregatedQuery is a query, which defines how responses to it are combined.
+// Queries, which don't implement it, use the First strategy.
+type AggregatedQuery interface {
+	Strategy() Strategy
+}
+
+// Merger is a response, which can be merged with another response of the same query.
+type Merger interface {
+	Merge(other interface{}) interface{}
+}
+
+// Responder is a subscriber, which answers queries of a single type, sent by Manager.Request.
+// Responders are not called for dispatched events.
+type Responder interface {
+	TypedSubscriber
+	Respond(ctx context.Context, query IEvent) (interface{}, error)
+}
+
+// Respond subscribes the function to queries of a single type, defined by the query argument.
+// The function must have one of the following signatures, where Q is the query type and R is the response type:
+//
+//	func(Q) R
+//	func(context.Context, Q) (R, error)
+//
+// A zero response means that the responder has nothing to answer.
+// Responders are called concurrently, so they must not update views directly.
+func Respond(fn interface{}) ISubscriber {
+	return RespondWithPrio(0, fn)
+}
+
+func RespondWithPrio(prio float64, fn interface{}) ISubscriber {
+	val := reflect.ValueOf(fn)
+	t := val.Type()
+	withCtx := t.Kind() == reflect.Func && t.NumIn() == 2 && t.In(0) == contextInterface &&
+		t.NumOut() == 2 && t.Out(1) == errorInterface
+	simple := t.Kind() == reflect.Func && t.NumIn() == 1 && t.NumOut() == 1
+	if !withCtx && !simple {
+		panic(fmt.Sprintf("events: invalid responder %s, expected func(Q) R or func(context.Context, Q) (R, error)", t))
+	}
+
+	s := responder{name: funcName(fn)}
+	s.prio = prio
+	s.handler = func(e IEvent) IEvent { return e }
+	if withCtx {
+		s.queryType = t.In(1)
+		s.respond = func(ctx context.Context, query IEvent) (interface{}, error) {
+			out := val.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(query)})
+			err, _ := out[1].Interface().(error)
+			return out[0].Interface(), err
+		}
+	} else {
+		s.queryType = t.In(0)
+		s.respond = func(_ context.Context, query IEvent) (interface{}, error) {
+			return val.Call([]reflect.Value{reflect.ValueOf(query)})[0].Interface(), nil
+		}
+	}
+	return s
+}
+
+var (
+	contextInterface = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorInterface   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+type responder struct {
+	subscriber
+	queryType reflect.Type
+	respond   func(ctx context.Context, query IEvent) (interface{}, error)
+	name      string
+}
+
+func (s responder) EventType() reflect.Type {
+	return s.queryType
+}
+
+func (s responder) Name() string {
+	return s.name
+}
+
+func (s responder) Respond(ctx context.Context, query IEvent) (interface{}, error) {
+	return s.respond(ctx, query)
+}
+
+// Request sends the query to its responders and combines their responses according to the query strategy.
+// If the context has no deadline, the default request timeout is applied.
+// When the timeout is exceeded, the responses received so far are combined.
+// An error is returned only if there is no response, but some of the responders have failed or timed out.
+func (em *DefaultManager) Request(ctx context.Context, query IEvent) (interface{}, error) {
+	return em.requestIn(ctx, query, nil)
+}
+
+func (em *DefaultManager) requestIn(ctx context.Context, query IEvent, domain *Scope) (interface{}, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, em.cfg.RequestTimeout)
+		defer cancel()
+	}
+
+	strategy := First
+	if aggregated, ok := query.(AggregatedQuery); ok {
+		strategy = aggregated.Strategy()
+	}
+
+	subs := em.responders(reflect.TypeOf(query), domain)
+	responses := make([]*response, len(subs))
+	received := make(chan *response, len(subs))
+	for i, sub := range subs {
+		go func(i int, sub *subscription) {
+			received <- em.ask(ctx, i, sub, query)
+		}(i, sub)
+	}
+
+	var err error
+wait:
+	for pending := len(subs); pending > 0; pending-- {
+		select {
+		case res := <-received:
+			responses[res.index] = res
+			if strategy == First && firstKnown(responses) {
+				break wait
+			}
+		case <-ctx.Done():
+			err = errors.WithMessagef(ctx.Err(), "request %T", query)
+			break wait
+		}
+	}
+
+	var results []interface{}
+	for _, res := range responses {
+		switch {
+		case res == nil:
+			continue
+		case res.err != nil:
+			if err == nil {
+				err = res.err
+			}
+		case !isZero(res.value):
+			results = append(results, res.value)
+		}
+	}
+	if len(results) == 0 {
+		return nil, err
+	}
+
+	switch strategy {
+	case All:
+		return results, nil
+	case Merge:
+		return merge(results)
+	default:
+		return results[0], nil
+	}
+}
+
+type response struct {
+	index int
+	value interface{}
+	err   error
+}
+
+// ask calls the responder and recovers it, if it panics.
+func (em *DefaultManager) ask(ctx context.Context, index int, sub *subscription, query IEvent) (res *response) {
+	res = &response{index: index}
+	defer func() {
+		if r := recover(); r != nil {
+			res.err = errors.Errorf("responder %s panicked: %v", sub.name, r)
+			em.fail(nil, sub, query, r, string(debug.Stack()))
+		}
+	}()
+	if res.value, res.err = sub.responder.Respond(ctx, query); res.err != nil {
+		res.err = errors.WithMessagef(res.err, "responder %s", sub.name)
+	}
+	return res
+}
+
+// responders returns all responders of the query type, which are reachable from the domain,
+// in order of their priorities.
+func (em *DefaultManager) responders(queryType reflect.Type, domain *Scope) []*subscription {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	var subs []*subscription
+	for _, sub := range em.sub {
+		if sub.responder != nil && sub.handles(queryType) && sub.reaches(domain) && atomic.LoadInt32(&sub.removed) == 0 {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// firstKnown tells whether the first non-zero response is received from all responders with higher priorities.
+func firstKnown(responses []*response) bool {
+	for _, res := range responses {
+		if res == nil {
+			return false
+		}
+		if res.err == nil && !isZero(res.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func isZero(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(value, reflect.Zero(v.Type()).Interface())
+}
+
+func merge(results []interface{}) (interface{}, error) {
+	merged := results[0]
+	for _, result := range results[1:] {
+		if merger, ok := merged.(Merger); ok {
+			merged = merger.Merge(result)
+			continue
+		}
+
+		a, b := reflect.ValueOf(merged), reflect.ValueOf(result)
+		if a.Type() != b.Type() {
+			return nil, errors.Errorf("could not merge responses of different types %T and %T", merged, result)
+		}
+		switch a.Kind() {
+		case reflect.Slice:
+			items := reflect.MakeSlice(a.Type(), 0, a.Len()+b.Len())
+			merged = reflect.AppendSlice(reflect.AppendSlice(items, a), b).Interface()
+		case reflect.Map:
+			items := reflect.MakeMapWithSize(a.Type(), a.Len()+b.Len())
+			for _, m := range []reflect.Value{a, b} {
+				for _, key := range m.MapKeys() {
+					items.SetMapIndex(key, m.MapIndex(key))
+				}
+			}
+			merged = items.Interface()
+		default:
+			return nil, errors.Errorf("could not merge responses of type %T", merged)
+		}
+	}
+	return merged, nil
+}
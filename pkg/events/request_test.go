@@ -0,0 +1,132 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type FindBirds struct {
+	By Strategy
+}
+
+func (q FindBirds) Strategy() Strategy {
+	return q.By
+}
+
+func TestRequest(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should return nothing if there are no responders", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		result, err := mng.Request(context.Background(), FindBirds{})
+		assert.NoError(err)
+		assert.Nil(result)
+	})
+
+	t.Run("should return the first non-zero response by priority", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		mng.Subscribe(
+			RespondWithPrio(1, func(q FindBirds) []string { return []string{"owl"} }),
+			RespondWithPrio(2, func(q FindBirds) []string { return nil }),
+			RespondWithPrio(3, func(ctx context.Context, q FindBirds) ([]string, error) {
+				time.Sleep(10 * time.Millisecond)
+				return []string{"eagle"}, nil
+			}),
+		)
+
+		result, err := mng.Request(context.Background(), FindBirds{By: First})
+		assert.NoError(err)
+		assert.Equal([]string{"eagle"}, result)
+	})
+
+	t.Run("should return all non-zero responses", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		mng.Subscribe(
+			RespondWithPrio(1, func(q FindBirds) string { return "owl" }),
+			RespondWithPrio(2, func(q FindBirds) string { return "" }),
+			RespondWithPrio(3, func(q FindBirds) string { return "eagle" }),
+		)
+
+		result, err := mng.Request(context.Background(), FindBirds{By: All})
+		assert.NoError(err)
+		assert.Equal([]interface{}{"eagle", "owl"}, result)
+	})
+
+	t.Run("should merge responses", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		mng.Subscribe(
+			RespondWithPrio(1, func(q FindBirds) []string { return []string{"owl", "crow"} }),
+			RespondWithPrio(2, func(q FindBirds) []string { return []string{"eagle"} }),
+		)
+
+		result, err := mng.Request(context.Background(), FindBirds{By: Merge})
+		assert.NoError(err)
+		assert.Equal([]string{"eagle", "owl", "crow"}, result)
+	})
+
+	t.Run("should return responses received before the timeout", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{RequestTimeout: 20 * time.Millisecond})
+		assert.NoError(err)
+
+		mng.Subscribe(
+			RespondWithPrio(2, func(ctx context.Context, q FindBirds) (string, error) {
+				<-ctx.Done()
+				return "", ctx.Err()
+			}),
+			RespondWithPrio(1, func(q FindBirds) string { return "owl" }),
+		)
+
+		result, err := mng.Request(context.Background(), FindBirds{By: First})
+		assert.NoError(err)
+		assert.Equal("owl", result)
+	})
+
+	t.Run("should return an error if there are no responses", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		mng.Subscribe(
+			Respond(func(ctx context.Context, q FindBirds) (string, error) { return "", errors.New("no birds") }),
+			Respond(func(q FindBirds) string { panic("boom") }),
+		)
+
+		_, err = mng.Request(context.Background(), FindBirds{})
+		assert.Error(err)
+	})
+
+	t.Run("should not call responders for dispatched events", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		responded := false
+		mng.Subscribe(Respond(func(q FindBirds) string {
+			responded = true
+			return "owl"
+		}))
+
+		mng.Dispatch(FindBirds{})
+		assert.False(responded)
+	})
+
+	t.Run("should not call removed responders", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		mng.Subscribe(Respond(func(q FindBirds) string { return "owl" })).Unsubscribe()
+
+		result, err := mng.Request(context.Background(), FindBirds{})
+		assert.NoError(err)
+		assert.Nil(result)
+	})
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Scope is a Manager, which keeps track of all subscriptions made through it,
+// so that they could be removed at once, when the owner of the scope is closed (e.g. a module or a tab).
+// Scopes can be nested: closing a scope also removes subscriptions of its child scopes.
+type Scope struct {
+	Manager
+	mu     *sync.Mutex
+	subs   []Subscription
+	closed bool
+	// the nearest isolated scope (the scope itself, if it's isolated), nil if there is none
+	domain *Scope
+	// the nearest isolated scope, which contains this isolated one
+	outer *Scope
+}
+
+func NewScope(parent Manager) *Scope {
+	s := &Scope{Manager: parent, mu: &sync.Mutex{}}
+	if p, ok := parent.(*Scope); ok {
+		s.domain = p.domain
+	}
+	return s
+}
+
+// NewIsolatedScope creates a scope, whose events don't leak to other isolated scopes (e.g. to other tabs).
+// Events dispatched within the scope (or its children) are handled only by the subscribers of the scope,
+// of the isolated scopes containing it and by the subscribers outside of any isolated scope.
+// Events dispatched outside are handled by all the nested isolated scopes as well.
+// Queries are isolated the same way.
+func NewIsolatedScope(parent Manager) *Scope {
+	s := NewScope(parent)
+	s.outer = s.domain
+	s.domain = s
+	return s
+}
+
+// contains tells whether the isolated scope is the other one, or contains it.
+func (s *Scope) contains(other *Scope) bool {
+	for ; other != nil; other = other.outer {
+		if other == s {
+			return true
+		}
+	}
+	return false
+}
+
+// domainManager is a manager, which supports isolated scopes.
+type domainManager interface {
+	dispatchIn(ctx context.Context, e IEvent, domain *Scope)
+	subscribeIn(domain *Scope, subscribers ...ISubscriber) Subscription
+	requestIn(ctx context.Context, query IEvent, domain *Scope) (interface{}, error)
+}
+
+func (s *Scope) Dispatch(e IEvent) {
+	s.dispatchIn(nil, e, s.domain)
+}
+
+func (s *Scope) DispatchContext(ctx context.Context, e IEvent) {
+	s.dispatchIn(ctx, e, s.domain)
+}
+
+// Subscribe adds the subscribers to the parent manager.
+// If the scope is already closed, the subscribers are removed immediately.
+func (s *Scope) Subscribe(subscribers ...ISubscriber) Subscription {
+	return s.subscribeIn(s.domain, subscribers...)
+}
+
+func (s *Scope) Request(ctx context.Context, query IEvent) (interface{}, error) {
+	return s.requestIn(ctx, query, s.domain)
+}
+
+func (s *Scope) dispatchIn(ctx context.Context, e IEvent, domain *Scope) {
+	switch parent, ok := s.Manager.(domainManager); {
+	case ok:
+		parent.dispatchIn(ctx, e, domain)
+	case ctx == nil:
+		s.Manager.Dispatch(e)
+	default:
+		s.Manager.DispatchContext(ctx, e)
+	}
+}
+
+func (s *Scope) subscribeIn(domain *Scope, subscribers ...ISubscriber) Subscription {
+	var sub Subscription
+	if parent, ok := s.Manager.(domainManager); ok {
+		sub = parent.subscribeIn(domain, subscribers...)
+	} else {
+		sub = s.Manager.Subscribe(subscribers...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		sub.Unsubscribe()
+	} else {
+		s.subs = append(s.subs, sub)
+	}
+	return sub
+}
+
+func (s *Scope) requestIn(ctx context.Context, query IEvent, domain *Scope) (interface{}, error) {
+	if parent, ok := s.Manager.(domainManager); ok {
+		return parent.requestIn(ctx, query, domain)
+	}
+	return s.Manager.Request(ctx, query)
+}
+
+// Close removes all subscriptions of the scope.
+func (s *Scope) Close() error {
+	s.mu.Lock()
+	subs := s.subs
+	s.subs = nil
+	s.closed = true
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+	return nil
+}
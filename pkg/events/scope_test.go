@@ -0,0 +1,107 @@
+package events
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should remove all subscriptions of the scope and its children", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		mng.Subscribe(On(func(e Bird) { handled = append(handled, "global") }))
+
+		tab := NewScope(mng)
+		tab.Subscribe(On(func(e Bird) { handled = append(handled, "tab") }))
+		module := NewScope(tab)
+		module.Subscribe(On(func(e Bird) { handled = append(handled, "module") }))
+
+		module.Dispatch(Bird{Name: "eagle"})
+		assert.Equal([]string{"global", "tab", "module"}, handled)
+
+		handled = nil
+		assert.NoError(tab.Close())
+		mng.Dispatch(Bird{Name: "owl"})
+		assert.Equal([]string{"global"}, handled)
+	})
+
+	t.Run("should not subscribe to a closed scope", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		scope := NewScope(mng)
+		assert.NoError(scope.Close())
+
+		handled := 0
+		scope.Subscribe(On(func(e Bird) { handled++ }))
+		mng.Dispatch(Bird{Name: "eagle"})
+		assert.Equal(0, handled)
+	})
+
+	t.Run("should isolate events dispatched within isolated scopes", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		mng.Subscribe(On(func(e Bird) { handled = append(handled, "global:"+e.Name) }))
+
+		tab1 := NewIsolatedScope(mng)
+		NewScope(tab1).Subscribe(On(func(e Bird) { handled = append(handled, "tab1:"+e.Name) }))
+		tab2 := NewIsolatedScope(mng)
+		NewScope(tab2).Subscribe(On(func(e Bird) { handled = append(handled, "tab2:"+e.Name) }))
+
+		NewScope(tab1).Dispatch(Bird{Name: "eagle"})
+		tab2.Dispatch(Bird{Name: "owl"})
+		mng.Dispatch(Bird{Name: "crow"})
+
+		assert.Equal([]string{
+			"global:eagle", "tab1:eagle",
+			"global:owl", "tab2:owl",
+			"global:crow", "tab1:crow", "tab2:crow",
+		}, handled)
+	})
+
+	t.Run("should pass events between nested isolated scopes", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		var handled []string
+		tab := NewIsolatedScope(mng)
+		tab.Subscribe(On(func(e Bird) { handled = append(handled, "tab:"+e.Name) }))
+		pane1 := NewIsolatedScope(tab)
+		pane1.Subscribe(On(func(e Bird) { handled = append(handled, "pane1:"+e.Name) }))
+		pane2 := NewIsolatedScope(tab)
+		pane2.Subscribe(On(func(e Bird) { handled = append(handled, "pane2:"+e.Name) }))
+
+		NewScope(pane1).Dispatch(Bird{Name: "eagle"})
+		tab.Dispatch(Bird{Name: "owl"})
+
+		assert.Equal([]string{
+			"tab:eagle", "pane1:eagle",
+			"tab:owl", "pane1:owl", "pane2:owl",
+		}, handled)
+	})
+
+	t.Run("should isolate queries sent within isolated scopes", func(t *testing.T) {
+		mng, err := NewManager(ManagerConfig{})
+		assert.NoError(err)
+
+		tab1 := NewIsolatedScope(mng)
+		tab1.Subscribe(Respond(func(q FindBirds) []string { return []string{"eagle"} }))
+		tab2 := NewIsolatedScope(mng)
+		tab2.Subscribe(Respond(func(q FindBirds) []string { return []string{"owl"} }))
+
+		result, err := NewScope(tab2).Request(context.Background(), FindBirds{By: Merge})
+		assert.NoError(err)
+		assert.Equal([]string{"owl"}, result)
+
+		result, err = mng.Request(context.Background(), FindBirds{By: Merge})
+		assert.NoError(err)
+		assert.Equal([]string{"eagle", "owl"}, result)
+	})
+}
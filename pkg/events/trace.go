@@ -0,0 +1,127 @@
+package events
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Trace describes how a dispatched event has been handled.
+type Trace struct {
+	// ID is a sequence number of the event in order of dispatching.
+	ID uint64
+	// Parent is ID of the event, whose handler has dispatched this one within its context (see On).
+	// It's zero, if the event has been dispatched outside of handlers (e.g. by user input).
+	Parent   uint64
+	Time     time.Time
+	Event    IEvent
+	Handlers []HandlerTrace
+	Duration time.Duration
+}
+
+// HandlerTrace describes a call of a single subscriber.
+type HandlerTrace struct {
+	Name     string
+	Priority float64
+	Duration time.Duration
+	// Changed tells whether the subscriber has replaced the event.
+	Changed bool
+	// Stopped tells whether the subscriber has stopped the propagation.
+	Stopped bool
+	// Failed tells whether the subscriber has panicked.
+	Failed bool
+}
+
+// Tracer receives traces of all events, handled by the manager.
+// It's called by the goroutine which has handled the event.
+type Tracer interface {
+	Trace(trace Trace)
+}
+
+// SetTracer enables tracing of the events, nil value disables it.
+func (em *DefaultManager) SetTracer(tracer Tracer) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	em.tracer = tracer
+}
+
+// EventType returns the event type by its name (see TypeName),
+// if the type is handled by any typed subscriber.
+func (em *DefaultManager) EventType(name string) (reflect.Type, bool) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	t, ok := em.types[name]
+	return t, ok
+}
+
+func (em *DefaultManager) getTracer() Tracer {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	return em.tracer
+}
+
+// track assigns the event an ID, if tracing is enabled.
+// The parent event is the one, whose handler has received the context.
+func (em *DefaultManager) track(ctx context.Context, e IEvent) dispatched {
+	if em.getTracer() == nil {
+		return dispatched{event: e}
+	}
+
+	d := dispatched{event: e, id: atomic.AddUint64(&em.lastEventID, 1)}
+	if h, ok := handlingOf(ctx); ok {
+		d.parent = h.id
+	}
+	return d
+}
+
+// TypeName returns a name of the type, which is unique within the app (e.g. "github.com/foo/bar.EventBaz").
+func TypeName(t reflect.Type) string {
+	if t.Name() == "" || t.PkgPath() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
+
+// changed tells whether the handler has returned another event.
+func changed(before, after IEvent) (result bool) {
+	if reflect.TypeOf(before) != reflect.TypeOf(after) {
+		return true
+	}
+	if !reflect.TypeOf(before).Comparable() {
+		return !reflect.DeepEqual(before, after)
+	}
+	// a comparable type could still contain an interface field with a non-comparable value
+	defer func() {
+		if recover() != nil {
+			result = !reflect.DeepEqual(before, after)
+		}
+	}()
+	return before != after
+}
+
+type namedSubscriber interface {
+	Name() string
+}
+
+func subscriberName(sub ISubscriber) string {
+	if named, ok := sub.(namedSubscriber); ok {
+		return named.Name()
+	}
+	return funcName(sub.Handler())
+}
+
+// funcName returns a short name of the function (e.g. "output.(*Module).handleEventOutput").
+func funcName(fn interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+	name := strings.TrimSuffix(f.Name(), "-fm")
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -50,6 +50,10 @@ func (Default) RemoveAll(path string) error {
 	return os.RemoveAll(path)
 }
 
+func (Default) Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
 func (Default) Split(path string) []string {
 	return strings.Split(path, string(os.PathSeparator))
 }
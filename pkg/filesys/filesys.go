@@ -22,6 +22,7 @@ type FileSys interface {
 	OpenFile(name string, flag int, perm os.FileMode) (File, error)
 	MkdirAll(path string, perm os.FileMode) error
 	RemoveAll(path string) error
+	Rename(oldPath, newPath string) error
 	Split(path string) []string
 	Join(parts ...string) string
 }
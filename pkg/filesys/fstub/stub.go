@@ -152,6 +152,16 @@ func (s *Stub) RemoveAll(pth string) error {
 	return nil
 }
 
+func (s *Stub) Rename(oldPath, newPath string) error {
+	f, exists := s.files[s.path(oldPath)]
+	if !exists {
+		return errors.Errorf("rename %s: no such file", oldPath)
+	}
+	delete(s.files, s.path(oldPath))
+	s.Root().Add(newPath, f)
+	return nil
+}
+
 func (s *Stub) Split(path string) []string {
 	return filesys.Default{}.Split(path)
 }
@@ -233,6 +233,23 @@ func TestStub(t *testing.T) {
 			assert.Empty(actual.Content())
 		})
 	})
+
+	t.Run("Rename", func(t *testing.T) {
+		t.Run("should move file to the new path", func(t *testing.T) {
+			stub := New(cfg)
+			stub.Root().Add("foo/bar.txt", NewFile("lorem ipsum"))
+
+			assert.NoError(stub.Rename("foo/bar.txt", "baz.txt"))
+			assert.Nil(stub.Get("foo/bar.txt"))
+			assert.Equal("baz.txt", stub.Get("baz.txt").Info.Name())
+			assert.Equal("lorem ipsum", stub.Get("baz.txt").ContentString())
+		})
+
+		t.Run("should return error if file does not exist", func(t *testing.T) {
+			stub := New(cfg)
+			assert.Error(stub.Rename("foo/bar.txt", "baz.txt"))
+		})
+	})
 }
 
 func assertFiles(t *testing.T, actual map[filePath]*FileStub, expected expectedFiles) {
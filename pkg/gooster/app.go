@@ -8,16 +8,41 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rivo/tview"
 	"io"
+	"runtime/debug"
 )
 
 type App struct {
 	*AppContext
-	cfg       AppConfig
-	root      *tview.Application
-	pages     *tview.Pages
-	modules   []moduleDefinition
-	focusMap  map[config.Key]tview.Primitive
+	cfg     AppConfig
+	root    *tview.Application
+	pages   *tview.Pages
+	modules []moduleDefinition
+	// names of the modules by their focus keys
+	focusKeys map[config.Key]string
 	lastFocus tview.Primitive
+	suspended int32
+	// event subscriptions of the modules of every tab
+	tabScopes map[string]*events.Scope
+	// modules of every tab in order of registration
+	tabModules map[string][]tabModule
+	currentTab string
+	tabs       []tab
+	tabBar     *tview.TextView
+	layout     *tview.Flex
+	// number of all tabs ever created, it's used to generate unique tab IDs
+	tabCount int
+	// panes of every tab (tabs without pane modules have no panes)
+	tabLayouts map[string]*tabLayout
+	// event subscriptions of the modules of every pane
+	paneScopes map[string]*events.Scope
+	// number of all panes ever created, it's used to generate unique pane IDs
+	paneCount int
+	// the file, which receives the recorded events (nil if recording is disabled)
+	recording filesys.File
+	// the events, which are recorded without their fields
+	secretEvents []events.IEvent
+	// closed when the app is stopped
+	stopped chan struct{}
 }
 
 func NewApp(cfgSource io.Reader, defaultCfgSource io.Reader) (*App, error) {
@@ -38,10 +63,14 @@ func NewApp(cfgSource io.Reader, defaultCfgSource io.Reader) (*App, error) {
 	root := tview.NewApplication()
 
 	ctx, err := NewAppContext(AppContextConfig{
-		LogLevel:          appCfg.LogLevel,
-		DelayEventManager: true,
-		FileSys:           filesys.Default{},
-		ConfigReader:      configReader,
+		LogLevel:              appCfg.LogLevel,
+		DelayEventManager:     true,
+		QueuedEvents:          appCfg.Events.Queued,
+		EventQueueSize:        appCfg.Events.QueueSize,
+		EventExecutor:         uiExecutor(root),
+		MaxSubscriberFailures: appCfg.Events.MaxFailures,
+		FileSys:               filesys.Default{},
+		ConfigReader:          configReader,
 	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "init app context")
@@ -52,12 +81,29 @@ func NewApp(cfgSource io.Reader, defaultCfgSource io.Reader) (*App, error) {
 	pages := tview.NewPages()
 	pages.SetBackgroundColor(tcell.ColorDefault)
 
+	tabBar := tview.NewTextView()
+	tabBar.SetDynamicColors(true)
+	tabBar.SetWrap(false)
+	tabBar.SetBackgroundColor(appCfg.Tabs.Colors.Bg.Origin())
+
+	layout := tview.NewFlex()
+	layout.SetDirection(tview.FlexRow)
+	layout.AddItem(tabBar, 0, 0, false)
+	layout.AddItem(pages, 0, 1, true)
+
 	app := &App{
 		AppContext: ctx,
 		cfg:        appCfg,
 		root:       root,
 		pages:      pages,
-		focusMap:   make(map[config.Key]tview.Primitive),
+		tabBar:     tabBar,
+		layout:     layout,
+		focusKeys:  make(map[config.Key]string),
+		tabScopes:  make(map[string]*events.Scope),
+		tabModules: make(map[string][]tabModule),
+		tabLayouts: make(map[string]*tabLayout),
+		paneScopes: make(map[string]*events.Scope),
+		stopped:    make(chan struct{}),
 	}
 
 	ctx.log.Info("App is initialized")
@@ -65,57 +111,128 @@ func NewApp(cfgSource io.Reader, defaultCfgSource io.Reader) (*App, error) {
 	return app, nil
 }
 
-func (app *App) RegisterModule(mod Module, extensions ...Extension) {
+// uiExecutor runs handlers of queued events on the UI goroutine,
+// so that they could safely update the views, and redraws the screen after every event.
+func uiExecutor(root *tview.Application) func(fn func()) {
+	return func(fn func()) {
+		done := make(chan struct{})
+		root.QueueUpdateDraw(func() {
+			defer close(done)
+			fn()
+		})
+		<-done
+	}
+}
+
+// RegisterModule adds a module to every tab. The factories are called for every new tab,
+// so that every tab gets its own independent instances of the module and its extensions.
+func (app *App) RegisterModule(mod ModuleFactory, extensions ...ExtensionFactory) {
 	app.modules = append(app.modules, moduleDefinition{
 		module:     mod,
 		extensions: extensions,
 	})
 }
 
-func (app *App) Run() {
-	// init event handlers
-	app.Events().Subscribe(events.HandleWithPrio(events.AfterAllOtherChanges, func(e events.IEvent) events.IEvent {
-		switch e.(type) {
-		case EventExit:
-			app.handleExitEvent()
+// Run starts the app and blocks until it's stopped.
+// A panic, which is not recovered by the event manager, stops the app and is returned as an error.
+func (app *App) Run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("app panicked: %v\n%s", r, debug.Stack())
 		}
-		return e
-	}))
-	app.Events().Subscribe(events.HandleFunc(func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case EventSetFocus:
-			app.handleSetFocusEvent(event)
-		case EventDraw:
-			app.handleDrawEvent()
-		case EventOpenDialog:
-			app.handleEventOpenDialog(event)
-		case EventCloseDialog:
-			app.handleEventCloseDialog()
-		case EventAddTab:
-			app.handleEventAddTab(event)
-		case EventShowTab:
-			app.handleEventShowTab(event)
-		case EventRemoveTab:
-			app.handleEventRemoveTab(event)
+	}()
+
+	if err := app.setup(); err != nil {
+		return err
+	}
+
+	// start the app
+	app.Log().Info("Starting App")
+	if err := app.root.Run(); err != nil {
+		return errors.WithMessage(err, "run app")
+	}
+	return nil
+}
+
+// setup initializes all modules and handlers.
+func (app *App) setup() error {
+	if app.cfg.Events.Record {
+		if err := app.startRecording(); err != nil {
+			app.Log().Error(err)
 		}
-		return e
-	}))
+	}
 
-	app.Events().Dispatch(EventAddTab{Id: initialTabId, View: app.createMainGrid()})
+	// init event handlers
+	app.Events().Subscribe(
+		events.OnWithPrio(events.AfterAllOtherChanges, func(EventExit) { app.handleExitEvent() }),
+		events.On(app.handleSetFocusEvent),
+		events.On(app.handleEventSetFocusByName),
+		events.On(func(EventDraw) { app.handleDrawEvent() }),
+		events.On(app.handleEventQueueUpdate),
+		events.On(app.handleEventSuspend),
+		events.On(app.handleEventOpenDialog),
+		events.On(func(EventCloseDialog) { app.handleEventCloseDialog() }),
+		events.On(app.handleEventAddTab),
+		events.On(app.handleEventShowTab),
+		events.On(app.handleEventRemoveTab),
+		events.On(app.handleEventRenameTab),
+		events.OnWithPrio(events.AfterAllOtherChanges, app.handleEventTabClosed),
+		events.On(app.handleEventSplitPane),
+		events.On(func(EventClosePane) { app.handleEventClosePane() }),
+		events.On(app.handleEventResizePane),
+		events.On(app.handleEventFocusPane),
+		events.On(app.handleEventSetModuleHeight),
+		events.OnWithPrio(events.AfterAllOtherChanges, app.handleEventPaneClosed),
+		events.On(app.handleEventRestoreSession),
+		events.On(func(EventSaveSession) { app.handleEventSaveSession() }),
+		// the session is saved before the modules release their state
+		events.OnWithPrio(float64(events.BeforeAllOtherChanges), func(EventExit) { app.handleEventSaveSession() }),
+	)
+
+	app.Events().Dispatch(EventAddTab{Id: initialTabId, View: app.createMainGrid(initialTabId)})
+
+	if app.cfg.Session.File != "" {
+		if app.cfg.Session.Restore {
+			session, err := app.loadSession()
+			if err != nil {
+				app.Log().Error(errors.WithMessage(err, "restore session"))
+			} else if session != nil {
+				app.Events().Dispatch(EventRestoreSession{Session: *session})
+			}
+		}
+		if app.cfg.Session.SaveInterval > 0 {
+			app.saveSessionPeriodically()
+		}
+	}
 
 	// init services and views
 	if em, ok := app.Events().(DelayedEventManager); ok {
 		if err := em.Init(); err != nil {
-			panic(errors.WithMessage(err, "init event manager"))
+			return errors.WithMessage(err, "init event manager")
 		}
 	}
 
 	// init key handlers
-	HandleKeyEvents(app.root, app.withFocusKeys(KeyEventHandlers{
+	HandleKeyEvents(app.root, app.withTabKeys(app.withFocusKeys(KeyEventHandlers{
 		config.NewKey(tcell.KeyCtrlC):  app.handleKeyCtrlC,
 		config.NewKey(tcell.KeyEscape): app.handleKeyEscape,
 		app.cfg.Keys.Exit:              app.handleKeyExit,
-	}))
+		app.cfg.Keys.Record:            app.handleKeyRecord,
+		app.cfg.Keys.FocusNext:         app.handleKeyFocusNext,
+		app.cfg.Keys.FocusPrev:         app.handleKeyFocusPrev,
+		app.cfg.Keys.NewTab:            app.handleKeyNewTab,
+		app.cfg.Keys.CloseTab:          app.handleKeyCloseTab,
+		app.cfg.Keys.RenameTab:         app.handleKeyRenameTab,
+		app.cfg.Keys.NextTab:           app.handleKeyNextTab,
+		app.cfg.Keys.PrevTab:           app.handleKeyPrevTab,
+		app.cfg.Keys.SplitRight:        app.handleKeySplitRight,
+		app.cfg.Keys.SplitDown:         app.handleKeySplitDown,
+		app.cfg.Keys.ClosePane:         app.handleKeyClosePane,
+		app.cfg.Keys.NextPane:          app.handleKeyNextPane,
+		app.cfg.Keys.PrevPane:          app.handleKeyPrevPane,
+		app.cfg.Keys.GrowPane:          app.handleKeyGrowPane,
+		app.cfg.Keys.ShrinkPane:        app.handleKeyShrinkPane,
+	})))
 
 	// debug keys
 	prev := app.root.GetInputCapture()
@@ -124,65 +241,101 @@ func (app *App) Run() {
 		return prev(event)
 	})
 
-	app.root.SetRoot(app.pages, true)
-
-	// start the app
-	app.Log().Info("Starting App")
-	if err := app.root.Run(); err != nil {
-		panic(errors.WithMessage(err, "run app"))
-	}
+	app.root.SetRoot(app.layout, true)
+	return nil
 }
 
-func (app *App) createMainGrid() tview.Primitive {
+// createMainGrid initializes new instances of all modules for the tab.
+// Modules, which fail to initialize, are skipped.
+func (app *App) createMainGrid(tabId string) tview.Primitive {
+	tabCtx, scope := app.AppContext.isolated()
+	app.tabScopes[tabId] = scope
+
 	grid := tview.NewGrid()
 	grid.SetBackgroundColor(tcell.ColorDefault)
 	grid.SetColumns(app.cfg.Grid.Cols...)
-	grid.SetRows(app.cfg.Grid.Rows...)
+	rows := newGridRows(grid, 0, app.cfg.Grid.Rows)
 
+	layout := newTabLayout(tabCtx)
 	for _, def := range app.modules {
-		mod, cfg, err := app.initModule(def.module, def.extensions...)
+		mod := def.module()
+		cfg, err := tabCtx.moduleConfig(mod)
 		if err != nil {
-			panic(err)
+			app.Log().Error(errors.WithMessagef(err, "Failed to load config for module %T", mod))
+			continue
 		}
-
-		focusKey := cfg.FocusKey
-		if !focusKey.Empty() {
-			app.focusMap[focusKey] = mod.View()
+		if cfg.Pane {
+			// pane modules are initialized for every pane separately
+			layout.addModule(def, cfg)
+			continue
 		}
 
-		if mod != nil {
-			grid.AddItem(
-				mod.View(),
-				cfg.Row, cfg.Col,
-				cfg.Height, cfg.Width,
-				0, 0,
-				cfg.Focused,
-			)
+		view := app.createModule(tabCtx, tabId, "", rows, def, mod, cfg)
+		if view != nil {
+			grid.AddItem(view, cfg.Row, cfg.Col, cfg.Height, cfg.Width, 0, 0, cfg.Focused)
 		}
+	}
 
+	if len(layout.defs) > 0 {
+		app.tabLayouts[tabId] = layout
+		layout.root = app.createPane(tabId, layout).node
+		layout.rebuild(app.cfg.Panes)
+		area := layout.area
+		grid.AddItem(layout.container, area.Row, area.Col, area.Height, area.Width, 0, 0, layout.focused)
 	}
 	return grid
 }
 
-func (app *App) initModule(mod Module, extensions ...Extension) (Module, *ModuleConfig, error) {
-	modCtx := app.AppContext.forModule(mod)
-	modCfg := defaultModConfig
-	err := modCtx.LoadConfig(&modCfg)
-	if err != nil {
-		return nil, nil, errors.WithMessagef(err, "Failed to load config for module %T", mod)
+// createModule initializes the module and its extensions and registers it in the tab (and the pane, if it's given).
+// It returns the view to be placed in the grid with the rows, or nil if the module has failed to initialize.
+func (app *App) createModule(ctx *AppContext, tabId, paneId string, rows *gridRows, def moduleDefinition, mod Module, cfg ModuleConfig) tview.Primitive {
+	var extensions []Extension
+	for _, ext := range def.extensions {
+		extensions = append(extensions, ext())
+	}
+	if err := app.initModule(ctx, mod, cfg, extensions...); err != nil {
+		app.Log().Error(err)
+		return nil
 	}
 
+	app.tabModules[tabId] = append(app.tabModules[tabId], tabModule{
+		name:      mod.Name(),
+		view:      mod.View(),
+		focusable: cfg.Focusable,
+		pane:      paneId,
+		rows:      rows,
+		row:       cfg.Row,
+	})
+
+	if cfg.Focusable && app.cfg.Focus.Indicator {
+		return newFocusFrame(mod.View(), app.cfg.Focus.Color.Origin())
+	}
+	return mod.View()
+}
+
+func (app *App) initModule(ctx *AppContext, mod Module, modCfg ModuleConfig, extensions ...Extension) (err error) {
+	modCtx, scope := ctx.forModule(mod)
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("Module %T panicked while initializing: %v\n%s", mod, r, debug.Stack())
+		}
+		if err != nil {
+			// the module is skipped, so its handlers must not receive events
+			_ = scope.Close()
+		}
+	}()
+
 	err = mod.Init(modCtx)
 	if err != nil {
-		return nil, nil, errors.WithMessagef(err, "Failed to init module %T", mod)
+		return errors.WithMessagef(err, "Failed to init module %T", mod)
 	}
 	for _, ext := range extensions {
-		extCtx := app.AppContext.forExtension(ext, mod)
+		extCtx := modCtx.forExtension(ext, mod)
 
 		extCfg := defaultExtConfig
 		err = extCtx.LoadConfig(&extCfg)
 		if err != nil {
-			return nil, nil, errors.WithMessagef(err, "Failed to load config for extension %T of module %T", ext, mod)
+			return errors.WithMessagef(err, "Failed to load config for extension %T of module %T", ext, mod)
 		}
 
 		if !extCfg.Enabled {
@@ -190,24 +343,38 @@ func (app *App) initModule(mod Module, extensions ...Extension) (Module, *Module
 		}
 
 		if err = ext.Init(mod, extCtx); err != nil {
-			return nil, nil, errors.WithMessagef(err, "Failed to init extension %T of module %T", ext, mod)
+			return errors.WithMessagef(err, "Failed to init extension %T of module %T", ext, mod)
 		}
 	}
 
 	if !modCfg.FocusKey.Empty() {
-		app.focusMap[modCfg.FocusKey] = mod.View()
+		app.focusKeys[modCfg.FocusKey] = mod.Name()
 	}
 
 	app.Log().InfoF("Initialized module [lightgreen]'%T'[-]", mod)
-	return mod, &modCfg, nil
+	return nil
 }
 
-// withFocusKeys adds handles for every focus key
+// withFocusKeys adds handles for every focus key, they focus the module of the current tab
 func (app *App) withFocusKeys(keyHandlers KeyEventHandlers) KeyEventHandlers {
-	for focusKey, view := range app.focusMap {
-		v := view
+	for focusKey, name := range app.focusKeys {
+		target := name
 		keyHandlers[focusKey] = func(event *tcell.EventKey) *tcell.EventKey {
-			app.Events().Dispatch(EventSetFocus{Target: v})
+			app.Events().Dispatch(EventSetFocusByName{TargetName: target})
+			return nil
+		}
+	}
+	return keyHandlers
+}
+
+// withTabKeys adds handlers for the keys, which show the tab by its position
+func (app *App) withTabKeys(keyHandlers KeyEventHandlers) KeyEventHandlers {
+	for i, key := range app.cfg.Keys.GoToTab {
+		pos := i
+		keyHandlers[key] = func(event *tcell.EventKey) *tcell.EventKey {
+			if pos < len(app.tabs) {
+				app.Events().Dispatch(EventShowTab{TabId: app.tabs[pos].id})
+			}
 			return nil
 		}
 	}
@@ -215,6 +382,6 @@ func (app *App) withFocusKeys(keyHandlers KeyEventHandlers) KeyEventHandlers {
 }
 
 type moduleDefinition struct {
-	module     Module
-	extensions []Extension
+	module     ModuleFactory
+	extensions []ExtensionFactory
 }
@@ -2,6 +2,8 @@ package app
 
 import (
 	"bytes"
+	"fmt"
+	"github.com/jumale/gooster/pkg/events"
 	"github.com/jumale/gooster/pkg/gooster"
 	"github.com/jumale/gooster/pkg/gooster/module/complete"
 	completeExt "github.com/jumale/gooster/pkg/gooster/module/complete/ext"
@@ -12,10 +14,43 @@ import (
 	"github.com/jumale/gooster/pkg/gooster/module/workdir"
 	workdirExt "github.com/jumale/gooster/pkg/gooster/module/workdir/ext"
 	"github.com/pkg/errors"
+	"io"
 	"os"
 )
 
 func Run(cfgPath string) {
+	if err := newShell(cfgPath).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// UnsafeEvents are not handled while replaying a session, unless it's explicitly allowed,
+// since they run commands, or change the file system.
+var UnsafeEvents = []events.IEvent{
+	prompt.EventExecCommand{},
+	prompt.EventSendUserInput{},
+	workdir.EventCreateFile{},
+	workdir.EventCreateDir{},
+	workdir.EventDelete{},
+	workdir.EventOpen{},
+	output.EventSaveBlock{},
+	gooster.EventExit{},
+}
+
+// SecretEvents are recorded without their fields, since they may contain passwords
+// or commands, which are not kept in the history.
+var SecretEvents = []events.IEvent{
+	prompt.EventExecCommand{},
+	prompt.EventSendUserInput{},
+}
+
+// Replay replays the recorded session on a virtual screen and returns the text of the final screen.
+func Replay(cfgPath string, session io.Reader, cfg gooster.ReplayConfig) (string, error) {
+	return newShell(cfgPath).Replay(session, cfg)
+}
+
+func newShell(cfgPath string) *gooster.App {
 	cfgFile, err := os.Open(cfgPath)
 	if err != nil {
 		panic(errors.WithMessagef(err, "Failed to open config file '%s'", cfgPath))
@@ -26,25 +61,29 @@ func Run(cfgPath string) {
 		panic(err)
 	}
 
+	shell.RedactEvents(SecretEvents...)
 	shell.RegisterModule(
-		workdir.NewModule(),
-		workdirExt.NewSortTree(),
-		workdirExt.NewTypingSearch(),
+		func() gooster.Module { return workdir.NewModule() },
+		workdirExt.NewSortTree,
+		workdirExt.NewTypingSearch,
 	)
 	shell.RegisterModule(
-		output.NewModule(),
+		func() gooster.Module { return output.NewModule() },
 	)
 	shell.RegisterModule(
-		prompt.NewModule(),
+		func() gooster.Module { return prompt.NewModule() },
 	)
 	shell.RegisterModule(
-		status.NewModule(),
-		statusExt.NewWorkDir(),
+		func() gooster.Module { return status.NewModule() },
+		statusExt.NewWorkDir,
+		statusExt.NewLastCommand,
+		statusExt.NewSearch,
+		statusExt.NewFailures,
 	)
 	shell.RegisterModule(
-		complete.NewModule(),
-		completeExt.NewBashCompletion(),
+		func() gooster.Module { return complete.NewModule() },
+		completeExt.NewBashCompletion,
 	)
 
-	shell.Run()
+	return shell
 }
@@ -17,6 +17,8 @@ modules:
     row: 1
     width: 1
     height: 1
+    focus_key: Ctrl-O
+    pane: true
     extensions: []
   
   - '#id': prompt
@@ -26,6 +28,7 @@ modules:
     height: 1
     focused: true
     focus_key: Ctrl-F
+    pane: true
     extensions: []
   
   - '#id': status
@@ -33,14 +36,19 @@ modules:
     row: 0
     width: 2
     height: 1
+    focusable: false
     extensions:
       - '#id': workdir
+      - '#id': last_command
+      - '#id': search
+      - '#id': failures
 
   - '#id': complete
     col: 1
     row: 3
     width: 1
     height: 1
+    pane: true
     extensions:
       - '#id': bash_completion
 `
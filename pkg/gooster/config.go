@@ -12,6 +12,58 @@ type AppConfig struct {
 	Keys     KeysConfig    `json:"keys"`
 	LogLevel log.Level     `json:"log_level"`
 	Dialog   dialog.Config `json:"dialog"`
+	Events   EventsConfig  `json:"events"`
+	Focus    FocusConfig   `json:"focus"`
+	Tabs     TabsConfig    `json:"tabs"`
+	Panes    PanesConfig   `json:"panes"`
+	Session  SessionConfig `json:"session"`
+}
+
+type SessionConfig struct {
+	// File keeps the session between restarts, empty value disables saving of the session.
+	File string `json:"file"`
+	// Restore opens the tabs of the last session on startup.
+	Restore bool `json:"restore"`
+	// SaveInterval is the number of seconds between saves of the session, zero value saves it only on exit.
+	SaveInterval int `json:"save_interval"`
+}
+
+type PanesConfig struct {
+	// Separator is the color of the line between the panes
+	Separator config.Color `json:"separator"`
+}
+
+type TabsConfig struct {
+	// AutoHide hides the tab bar, while there is only one tab
+	AutoHide bool             `json:"auto_hide"`
+	Colors   TabsColorsConfig `json:"colors"`
+}
+
+type TabsColorsConfig struct {
+	Bg       config.Color `json:"bg"`
+	Fg       config.Color `json:"fg"`
+	ActiveBg config.Color `json:"active_bg"`
+	ActiveFg config.Color `json:"active_fg"`
+}
+
+type FocusConfig struct {
+	// Indicator reserves the left column of every focusable module for a bar, which marks the focused one.
+	// It's disabled by default, since it narrows every module.
+	Indicator bool         `json:"indicator"`
+	Color     config.Color `json:"color"`
+}
+
+type EventsConfig struct {
+	// Queued makes events to be handled one by one on the UI goroutine,
+	// instead of being handled synchronously by the goroutine, which dispatched them.
+	Queued    bool `json:"queued"`
+	QueueSize int  `json:"queue_size"`
+	// Record enables recording of all events to the RecordFile from the start,
+	// the recording can be also toggled by the key.
+	Record     bool   `json:"record"`
+	RecordFile string `json:"record_file"`
+	// MaxFailures is the number of panics, after which an event handler is disabled (negative never disables).
+	MaxFailures int `json:"max_failures"`
 }
 
 type GridConfig struct {
@@ -20,7 +72,27 @@ type GridConfig struct {
 }
 
 type KeysConfig struct {
-	Exit config.Key `json:"exit"`
+	Exit   config.Key `json:"exit"`
+	Record config.Key `json:"record"`
+	// FocusNext and FocusPrev cycle the focus over all focusable modules of the current tab.
+	// FocusPrev is Shift-F6 (reported as F18 by terminals), since Shift-Tab is used by the dialog forms.
+	FocusNext config.Key `json:"focus_next"`
+	FocusPrev config.Key `json:"focus_prev"`
+	NewTab    config.Key `json:"new_tab"`
+	CloseTab  config.Key `json:"close_tab"`
+	RenameTab config.Key `json:"rename_tab"`
+	NextTab   config.Key `json:"next_tab"`
+	PrevTab   config.Key `json:"prev_tab"`
+	// GoToTab shows the tab by its position
+	GoToTab    []config.Key `json:"go_to_tab"`
+	SplitRight config.Key   `json:"split_right"`
+	SplitDown  config.Key   `json:"split_down"`
+	ClosePane  config.Key   `json:"close_pane"`
+	NextPane   config.Key   `json:"next_pane"`
+	PrevPane   config.Key   `json:"prev_pane"`
+	// GrowPane and ShrinkPane resize the active pane within its split
+	GrowPane   config.Key `json:"grow_pane"`
+	ShrinkPane config.Key `json:"shrink_pane"`
 }
 
 var defaultConfig = AppConfig{
@@ -30,7 +102,47 @@ var defaultConfig = AppConfig{
 		Rows: []int{1, -1, 1, 5},
 	},
 	Keys: KeysConfig{
-		Exit: config.NewKey(tcell.KeyF12),
+		Exit:       config.NewKey(tcell.KeyF12),
+		Record:     config.NewKey(tcell.KeyF9),
+		FocusNext:  config.NewKey(tcell.KeyF6),
+		FocusPrev:  config.NewKey(tcell.KeyF18),
+		NewTab:     config.NewKey(tcell.KeyRune).SetRune('t').AddMod(tcell.ModAlt),
+		CloseTab:   config.NewKey(tcell.KeyRune).SetRune('w').AddMod(tcell.ModAlt),
+		RenameTab:  config.NewKey(tcell.KeyRune).SetRune('r').AddMod(tcell.ModAlt),
+		NextTab:    config.NewKey(tcell.KeyRune).SetRune('n').AddMod(tcell.ModAlt),
+		PrevTab:    config.NewKey(tcell.KeyRune).SetRune('p').AddMod(tcell.ModAlt),
+		GoToTab:    goToTabKeys(),
+		SplitRight: config.NewKey(tcell.KeyRune).SetRune('v').AddMod(tcell.ModAlt),
+		SplitDown:  config.NewKey(tcell.KeyRune).SetRune('s').AddMod(tcell.ModAlt),
+		ClosePane:  config.NewKey(tcell.KeyRune).SetRune('x').AddMod(tcell.ModAlt),
+		NextPane:   config.NewKey(tcell.KeyRune).SetRune('o').AddMod(tcell.ModAlt),
+		PrevPane:   config.NewKey(tcell.KeyRune).SetRune('O').AddMod(tcell.ModAlt),
+		GrowPane:   config.NewKey(tcell.KeyRune).SetRune('=').AddMod(tcell.ModAlt),
+		ShrinkPane: config.NewKey(tcell.KeyRune).SetRune('-').AddMod(tcell.ModAlt),
+	},
+	Tabs: TabsConfig{
+		AutoHide: true,
+		Colors: TabsColorsConfig{
+			Bg:       config.Color(tcell.NewHexColor(0x333333)),
+			Fg:       config.Color(tcell.ColorLightGray),
+			ActiveBg: config.Color(tcell.ColorCornflowerBlue),
+			ActiveFg: config.Color(tcell.ColorWhite),
+		},
+	},
+	Panes: PanesConfig{
+		Separator: config.Color(tcell.NewHexColor(0x333333)),
+	},
+	Session: SessionConfig{
+		File:         "~/.gooster/session.json",
+		SaveInterval: 60,
+	},
+	Focus: FocusConfig{
+		Color: config.Color(tcell.ColorCornflowerBlue),
+	},
+	Events: EventsConfig{
+		QueueSize:   1024,
+		RecordFile:  "~/.gooster_events.jsonl",
+		MaxFailures: 3,
 	},
 	Dialog: dialog.Config{
 		Colors: dialog.ColorsConfig{
@@ -39,3 +151,12 @@ var defaultConfig = AppConfig{
 		},
 	},
 }
+
+// goToTabKeys returns Alt-1..Alt-9 keys
+func goToTabKeys() []config.Key {
+	var keys []config.Key
+	for r := '1'; r <= '9'; r++ {
+		keys = append(keys, config.NewKey(tcell.KeyRune).SetRune(r).AddMod(tcell.ModAlt))
+	}
+	return keys
+}
@@ -2,6 +2,7 @@ package gooster
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"github.com/jumale/gooster/pkg/config"
 	"github.com/jumale/gooster/pkg/events"
@@ -26,8 +27,15 @@ type AppContextConfig struct {
 	LogFormat         string
 	LogTarget         io.Writer
 	DelayEventManager bool
-	ConfigReader      config.Reader
-	FileSys           filesys.FileSys
+	// QueuedEvents, EventQueueSize and EventExecutor configure the queued mode of the event manager,
+	// see events.ManagerConfig for details.
+	QueuedEvents   bool
+	EventQueueSize int
+	EventExecutor  func(fn func())
+	// MaxSubscriberFailures is the number of panics, after which an event subscriber is disabled.
+	MaxSubscriberFailures int
+	ConfigReader          config.Reader
+	FileSys               filesys.FileSys
 }
 
 type AppContext struct {
@@ -42,22 +50,34 @@ func NewAppContext(cfg AppContextConfig) (ctx *AppContext, err error) {
 	var em events.Manager
 	var logger log.Logger
 
-	em, err = events.NewManager(events.ManagerConfig{DelayedStart: cfg.DelayEventManager})
+	em, err = events.NewManager(events.ManagerConfig{
+		DelayedStart: cfg.DelayEventManager,
+		Queued:       cfg.QueuedEvents,
+		QueueSize:    cfg.EventQueueSize,
+		Executor:     cfg.EventExecutor,
+		MaxFailures:  cfg.MaxSubscriberFailures,
+	})
 	if err != nil {
 		return nil, errors.WithMessage(err, "init event manager")
 	}
 
-	em.Subscribe(events.HandleWithPrio(events.AfterAllOtherChanges, func(event events.IEvent) events.IEvent {
-		logEventToOutput(logger, event)
-
-		if drawable, ok := event.(DrawableEvent); ok {
-			if drawable.NeedsDraw() {
-				em.Dispatch(EventDraw{})
+	em.Subscribe(
+		events.HandleWithPrio(events.AfterAllOtherChanges, func(event events.IEvent) events.IEvent {
+			logEventToOutput(logger, event)
+			return event
+		}),
+		events.OnWithPrio(events.AfterAllOtherChanges, func(event events.EventSubscriberFailed) {
+			logger.ErrorF("%s\n%s", event.Error(), event.Stack)
+			if event.Disabled {
+				logger.ErrorF("Subscriber %s is disabled after %d failures", event.Subscriber, event.Failures)
 			}
-		}
-
-		return event
-	}))
+		}),
+		events.OnWithPrio(events.AfterAllOtherChanges, func(ctx context.Context, event DrawableEvent) {
+			if event.NeedsDraw() {
+				em.DispatchContext(ctx, EventDraw{})
+			}
+		}),
+	)
 
 	output := &output{em}
 
@@ -122,16 +142,42 @@ const (
 	extCfgPath    = "extensions[?(@.#id == '%s')][0]"
 )
 
-func (ctx *AppContext) forModule(mod Module) *AppContext {
-	newCtx := *ctx
+func (ctx *AppContext) forModule(mod Module) (*AppContext, *events.Scope) {
+	newCtx, scope := ctx.scoped()
 	newCtx.cfgPath = joinPath("$", fmt.Sprintf(moduleCfgPath, mod.Name()))
-	return &newCtx
+	return newCtx, scope
+}
+
+// moduleConfig reads config of the module, the module doesn't have to be initialized.
+func (ctx *AppContext) moduleConfig(mod Module) (ModuleConfig, error) {
+	modCtx := *ctx
+	modCtx.cfgPath = joinPath("$", fmt.Sprintf(moduleCfgPath, mod.Name()))
+	modCfg := defaultModConfig
+	err := modCtx.LoadConfig(&modCfg)
+	return modCfg, err
 }
 
 func (ctx *AppContext) forExtension(ext Extension, target Module) *AppContext {
-	newCtx := *ctx
+	newCtx, _ := ctx.scoped()
 	newCtx.cfgPath = joinPath("$", fmt.Sprintf(moduleCfgPath, target.Name()), fmt.Sprintf(extCfgPath, ext.Name()))
-	return &newCtx
+	return newCtx
+}
+
+// scoped returns a copy of the context, which subscribes to events in a new child scope,
+// so that all its subscriptions could be removed by closing the scope.
+func (ctx *AppContext) scoped() (*AppContext, *events.Scope) {
+	newCtx := *ctx
+	scope := events.NewScope(ctx.em)
+	newCtx.em = scope
+	return &newCtx, scope
+}
+
+// isolated returns a copy of the context, whose events don't reach subscribers of other isolated contexts.
+func (ctx *AppContext) isolated() (*AppContext, *events.Scope) {
+	newCtx := *ctx
+	scope := events.NewIsolatedScope(ctx.em)
+	newCtx.em = scope
+	return &newCtx, scope
 }
 
 func joinPath(vals ...string) string {
@@ -174,6 +220,8 @@ func logEventToOutput(logger log.Logger, event events.IEvent) {
 		return
 	case EventDraw:
 		return
+	case events.EventSubscriberFailed:
+		return
 	default:
 		msg := fmt.Sprintf("[gold]Event:[-] [lightseagreen]%T[-]%+v", event, event)
 		if len(msg) > 130 {
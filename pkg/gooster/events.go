@@ -7,6 +7,7 @@ import (
 	"github.com/jumale/gooster/pkg/completion"
 	"github.com/jumale/gooster/pkg/config"
 	"github.com/jumale/gooster/pkg/dialog"
+	"github.com/jumale/gooster/pkg/events"
 	"github.com/rivo/tview"
 )
 
@@ -14,6 +15,10 @@ type DelayedEventManager interface {
 	Init() error
 }
 
+type TracedEventManager interface {
+	SetTracer(events.Tracer)
+}
+
 type DrawableEvent interface {
 	NeedsDraw() bool
 }
@@ -34,6 +39,18 @@ type EventSetFocusByName struct {
 
 type EventDraw struct{}
 
+// EventQueueUpdate runs the function on the UI goroutine and redraws the screen,
+// e.g. to apply a result, which has been prepared by another goroutine.
+type EventQueueUpdate struct {
+	Update func()
+}
+
+// EventSuspend suspends the app and gives the real terminal to the Run function
+// (e.g. for running full-screen applications). The app is restored as soon as Run returns.
+type EventSuspend struct {
+	Run func()
+}
+
 type EventOutput struct {
 	Data []byte
 }
@@ -42,6 +59,15 @@ func (e EventOutput) NeedsDraw() bool {
 	return true
 }
 
+// QueryCompletion requests a completion (completion.Completion) of the latest command of the input.
+type QueryCompletion struct {
+	Input    string
+	Commands []command.Definition
+	// WorkDir is the work dir of the tab, since the process work dir could be changed by another tab
+	WorkDir string
+}
+
+// EventSetCompletion shows the completion suggestions to the user.
 type EventSetCompletion struct {
 	Input      string
 	Commands   []command.Definition
@@ -72,6 +98,81 @@ type EventRemoveTab struct {
 	TabId string
 }
 
+type EventRenameTab struct {
+	TabId string
+	Title string
+}
+
+// EventTabActivated is dispatched within the tab, when it's shown,
+// so that its modules could restore their state shared with other tabs (e.g. the process work dir).
+type EventTabActivated struct {
+	TabId string
+}
+
+// EventTabDeactivated is dispatched within the tab, when another tab is shown.
+type EventTabDeactivated struct {
+	TabId string
+}
+
+// EventTabClosed is dispatched within the tab, when it's removed,
+// so that its modules could release their resources (e.g. stop running commands).
+type EventTabClosed struct {
+	TabId string
+}
+
+// EventSplitPane splits the active pane of the current tab, the new pane gets the focus.
+type EventSplitPane struct {
+	Direction SplitDirection
+}
+
+// EventClosePane closes the active pane of the current tab.
+type EventClosePane struct{}
+
+// EventResizePane changes proportion of the active pane within its split.
+type EventResizePane struct {
+	Delta int
+}
+
+// EventFocusPane moves the focus to the next (or previous, if the step is negative) pane of the current tab.
+type EventFocusPane struct {
+	Step int
+}
+
+// EventSetModuleHeight changes height of the grid row of the module (the first one, if the module spans several rows),
+// so that the module could grow with its content. The zero height restores the configured height of the row.
+type EventSetModuleHeight struct {
+	View   ModuleView
+	Height int
+}
+
+// EventPaneClosed is dispatched within the pane, when it's closed,
+// so that its modules could release their resources (e.g. stop running commands).
+type EventPaneClosed struct {
+	PaneId string
+}
+
+// QuerySessionState requests states of the modules (ModuleStates) to be saved in the session.
+// Every module responds with its own state, the responses are merged.
+type QuerySessionState struct{}
+
+func (q QuerySessionState) Strategy() events.Strategy {
+	return events.Merge
+}
+
+// EventRestoreState is dispatched within the tab, which is restored from the session,
+// so that its modules could restore their states.
+type EventRestoreState struct {
+	Modules ModuleStates
+}
+
+// EventRestoreSession opens the tabs of the session and restores their states.
+type EventRestoreSession struct {
+	Session Session
+}
+
+// EventSaveSession writes state of all tabs to the session file.
+type EventSaveSession struct{}
+
 // ------------------------------------------------------------ //
 
 type KeyEventHandler func(event *tcell.EventKey) *tcell.EventKey
@@ -5,6 +5,9 @@ type Extension interface {
 	Init(Module, Context) error
 }
 
+// ExtensionFactory creates a new instance of the extension.
+type ExtensionFactory func() Extension
+
 type ExtensionConfig struct {
 	Enabled bool `json:"enabled"`
 }
@@ -0,0 +1,135 @@
+package gooster
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// tabModule is a module registered in a tab, which can be focused by its name.
+type tabModule struct {
+	name      string
+	view      ModuleView
+	focusable bool
+	// ID of the pane, which contains the module (empty for the modules of the whole tab)
+	pane string
+	// the rows of the grid, which contains the module, and the (first) row of the module
+	rows *gridRows
+	row  int
+}
+
+// currentModules returns the modules of the current tab, excluding the ones of the inactive panes.
+func (app *App) currentModules() []tabModule {
+	activePane := ""
+	if layout, ok := app.tabLayouts[app.currentTab]; ok {
+		activePane = layout.activePane().id
+	}
+
+	var mods []tabModule
+	for _, mod := range app.tabModules[app.currentTab] {
+		if mod.pane == "" || mod.pane == activePane {
+			mods = append(mods, mod)
+		}
+	}
+	return mods
+}
+
+// moduleByName returns view of the module of the current tab (and of its active pane).
+func (app *App) moduleByName(name string) ModuleView {
+	for _, mod := range app.currentModules() {
+		if mod.name == name {
+			return mod.view
+		}
+	}
+	return nil
+}
+
+// focusedModule returns name of the focused module of the current tab, or empty string if none is focused.
+func (app *App) focusedModule() string {
+	for _, mod := range app.currentModules() {
+		if mod.view.GetFocusable().HasFocus() {
+			return mod.name
+		}
+	}
+	return ""
+}
+
+// cycleFocus moves the focus to the next (or previous, if the step is negative) focusable module
+// of the current tab, the modules of inactive panes are skipped.
+func (app *App) cycleFocus(step int) {
+	var ring []ModuleView
+	for _, mod := range app.currentModules() {
+		if mod.focusable {
+			ring = append(ring, mod.view)
+		}
+	}
+	if len(ring) == 0 {
+		return
+	}
+
+	// if none of the modules is focused, then the ring starts from the first one
+	current := len(ring) - 1
+	if step < 0 {
+		current = 0
+	}
+	for i, view := range ring {
+		if view.GetFocusable().HasFocus() {
+			current = i
+			break
+		}
+	}
+
+	next := ((current+step)%len(ring) + len(ring)) % len(ring)
+	app.Events().Dispatch(EventSetFocus{Target: ring[next]})
+}
+
+// focusFrame draws the module view and marks it by the indicator bar, when the view is focused.
+type focusFrame struct {
+	*tview.Box
+	view  ModuleView
+	color tcell.Color
+}
+
+func newFocusFrame(view ModuleView, color tcell.Color) *focusFrame {
+	return &focusFrame{Box: tview.NewBox(), view: view, color: color}
+}
+
+func (f *focusFrame) Draw(screen tcell.Screen) {
+	x, y, width, height := f.GetRect()
+	if width < 2 {
+		f.view.SetRect(x, y, width, height)
+		f.view.Draw(screen)
+		return
+	}
+
+	f.view.SetRect(x+1, y, width-1, height)
+	f.view.Draw(screen)
+
+	// the bar gets the background of the view, so that it looks like a part of it
+	_, _, viewStyle, _ := screen.GetContent(x+1, y)
+	_, bg, _ := viewStyle.Decompose()
+	bar := ' '
+	style := tcell.StyleDefault.Background(bg)
+	if f.view.GetFocusable().HasFocus() {
+		bar = '▎'
+		style = style.Foreground(f.color)
+	}
+	for row := y; row < y+height; row++ {
+		screen.SetContent(x, row, bar, nil, style)
+	}
+}
+
+func (f *focusFrame) Focus(delegate func(p tview.Primitive)) {
+	delegate(f.view)
+}
+
+func (f *focusFrame) HasFocus() bool {
+	return f.view.GetFocusable().HasFocus()
+}
+
+func (f *focusFrame) GetFocusable() tview.Focusable {
+	return f.view.GetFocusable()
+}
+
+func (f *focusFrame) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return f.view.InputHandler()
+}
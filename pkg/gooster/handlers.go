@@ -2,12 +2,17 @@ package gooster
 
 import (
 	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/dialog"
 	"github.com/pkg/errors"
 	"github.com/rivo/tview"
+	"strings"
+	"sync/atomic"
 )
 
 func (app *App) handleExitEvent() {
 	app.Log().Info("Stopping app")
+	app.stopRecording()
+	close(app.stopped)
 	if err := app.AppContext.close(); err != nil {
 		app.Log().Error(errors.WithMessage(err, "stopping app"))
 	}
@@ -15,9 +20,53 @@ func (app *App) handleExitEvent() {
 }
 
 func (app *App) handleDrawEvent() {
+	// the screen is finalized while the app is suspended
+	if atomic.LoadInt32(&app.suspended) == 1 {
+		return
+	}
 	app.root.Draw()
 }
 
+func (app *App) handleEventQueueUpdate(event EventQueueUpdate) {
+	// the function is not recorded, so it's missing in the replayed events
+	if event.Update != nil {
+		app.root.QueueUpdateDraw(event.Update)
+	}
+}
+
+func (app *App) handleEventSuspend(event EventSuspend) {
+	if !atomic.CompareAndSwapInt32(&app.suspended, 0, 1) {
+		app.Log().Error("Could not suspend the app: it's already suspended")
+		return
+	}
+
+	// the event can be dispatched from the UI event loop,
+	// which must keep running to pick up the new screen, when Run has finished
+	go func() {
+		focus, lastFocus := app.root.GetFocus(), app.lastFocus
+		app.Log().Debug("Suspending app")
+		app.root.Suspend(event.Run)
+		atomic.StoreInt32(&app.suspended, 0)
+
+		app.root.QueueUpdateDraw(func() {
+			app.Log().Debug("Resuming app")
+			if focus != nil {
+				app.root.SetFocus(focus)
+			}
+			app.lastFocus = lastFocus
+		})
+	}()
+}
+
+func (app *App) handleEventSetFocusByName(event EventSetFocusByName) {
+	view := app.moduleByName(event.TargetName)
+	if view == nil {
+		app.Log().ErrorF("Could not focus module '%s'. Not found.", event.TargetName)
+		return
+	}
+	app.Events().Dispatch(EventSetFocus{Target: view})
+}
+
 func (app *App) handleSetFocusEvent(event EventSetFocus) {
 	if event.Target != nil {
 		app.Log().DebugF("Focusing view: %T", event.Target)
@@ -54,6 +103,9 @@ func (app *App) handleEventCloseDialog() {
 }
 
 func (app *App) handleEventAddTab(event EventAddTab) {
+	if event.Id == "" {
+		event.Id = app.nextTabId()
+	}
 	pageId := event.pageId()
 	if app.pages.HasPage(pageId) {
 		app.Log().ErrorF("Can not add tab with ID '%s'. The ID must be unique, but such tab already exists.", event.Id)
@@ -61,46 +113,175 @@ func (app *App) handleEventAddTab(event EventAddTab) {
 	}
 
 	if event.View == nil {
-		event.View = app.createMainGrid()
+		event.View = app.createMainGrid(event.Id)
+	}
+	if event.Title == "" {
+		event.Title = defaultTabTitle
 	}
-
-	// @todo: implement tab title
 
 	app.log.DebugF("Creating a new tab '%s'", event.Id)
-	app.pages.AddPage(pageId, event.View, true, true)
+	app.tabCount++
+	app.tabs = append(app.tabs, tab{id: event.Id, title: event.Title, view: event.View})
+	app.pages.AddPage(pageId, event.View, true, false)
+	app.activateTab(event.Id)
 }
 
 func (app *App) handleEventShowTab(event EventShowTab) {
 	tabId := event.TabId
-	pageId := EventAddTab{Id: tabId}.pageId()
-	if !app.pages.HasPage(pageId) {
+	if app.tabIndex(tabId) < 0 {
 		app.Log().ErrorF("Could not show tab with ID '%s'. Not found.", tabId)
 		return
 	}
-	app.pages.ShowPage(pageId)
+	app.activateTab(tabId)
 }
 
 const initialTabId = "initial"
 
 func (app *App) handleEventRemoveTab(event EventRemoveTab) {
 	tabId := event.TabId
-	if tabId == initialTabId {
-		app.Log().Warn("Can not remove the initial tab.")
+	pos := app.tabIndex(tabId)
+	if pos < 0 {
+		app.Log().ErrorF("Could not remove tab with ID '%s'. Not found.", tabId)
+		return
+	}
+	if len(app.tabs) == 1 {
+		app.Log().Warn("Can not remove the last tab.")
 		return
 	}
 
-	pageId := EventAddTab{Id: tabId}.pageId()
-	if !app.pages.HasPage(pageId) {
-		app.Log().ErrorF("Could not remove tab with ID '%s'. Not found.", tabId)
+	app.pages.RemovePage(EventAddTab{Id: tabId}.pageId())
+	app.tabs = append(app.tabs[:pos], app.tabs[pos+1:]...)
+	if app.currentTab == tabId {
+		if pos == len(app.tabs) {
+			pos--
+		}
+		app.activateTab(app.tabs[pos].id)
+	} else {
+		app.renderTabBar()
+	}
+
+	if scope, ok := app.tabScopes[tabId]; ok {
+		// the modules release their resources, then the scope is closed by the app
+		scope.Dispatch(EventTabClosed{TabId: tabId})
+	}
+}
+
+func (app *App) handleEventTabClosed(event EventTabClosed) {
+	if scope, ok := app.tabScopes[event.TabId]; ok {
+		// the modules of the tab must not handle any events anymore
+		_ = scope.Close()
+		delete(app.tabScopes, event.TabId)
+	}
+	delete(app.tabModules, event.TabId)
+
+	// the pane scopes are closed together with the tab scope
+	if layout, ok := app.tabLayouts[event.TabId]; ok {
+		for _, p := range layout.root.leaves() {
+			delete(app.paneScopes, p.id)
+		}
+		delete(app.tabLayouts, event.TabId)
+	}
+}
+
+func (app *App) handleEventSplitPane(event EventSplitPane) {
+	if layout, ok := app.tabLayouts[app.currentTab]; ok {
+		app.splitPane(layout, event.Direction)
+	} else {
+		app.Log().Warn("Can not split the tab: it has no pane modules.")
+	}
+}
+
+func (app *App) handleEventClosePane() {
+	if layout, ok := app.tabLayouts[app.currentTab]; ok {
+		app.closePane(layout)
+	}
+}
+
+func (app *App) handleEventResizePane(event EventResizePane) {
+	if layout, ok := app.tabLayouts[app.currentTab]; ok {
+		app.resizePane(layout, event.Delta)
+	}
+}
+
+func (app *App) handleEventFocusPane(event EventFocusPane) {
+	if layout, ok := app.tabLayouts[app.currentTab]; ok {
+		app.focusPane(layout, event.Step)
+	}
+}
+
+func (app *App) handleEventSetModuleHeight(event EventSetModuleHeight) {
+	for _, mods := range app.tabModules {
+		for _, mod := range mods {
+			if mod.view == event.View && mod.rows != nil {
+				mod.rows.resize(mod.row, event.Height)
+				return
+			}
+		}
+	}
+}
+
+func (app *App) handleEventPaneClosed(event EventPaneClosed) {
+	if scope, ok := app.paneScopes[event.PaneId]; ok {
+		// the modules of the pane must not handle any events anymore
+		_ = scope.Close()
+		delete(app.paneScopes, event.PaneId)
+	}
+	for tabId, mods := range app.tabModules {
+		var kept []tabModule
+		for _, mod := range mods {
+			if mod.pane != event.PaneId {
+				kept = append(kept, mod)
+			}
+		}
+		app.tabModules[tabId] = kept
+	}
+}
+
+func (app *App) handleEventRenameTab(event EventRenameTab) {
+	pos := app.tabIndex(event.TabId)
+	if pos < 0 {
+		app.Log().ErrorF("Could not rename tab with ID '%s'. Not found.", event.TabId)
 		return
 	}
+	app.tabs[pos].title = event.Title
+	app.renderTabBar()
+}
+
+func (app *App) handleEventRestoreSession(event EventRestoreSession) {
+	for i, saved := range event.Session.Tabs {
+		// the first tab of the session is restored in the current tab
+		tabId := app.currentTab
+		if i > 0 {
+			tabId = app.nextTabId()
+			app.handleEventAddTab(EventAddTab{Id: tabId, Title: saved.Title})
+		}
 
-	app.pages.RemovePage(pageId)
+		t := &app.tabs[app.tabIndex(tabId)]
+		if saved.Title != "" {
+			t.title = saved.Title
+		}
+		t.focused = saved.Focused
+		app.tabScope(tabId).Dispatch(EventRestoreState{Modules: saved.Modules})
+	}
+	app.renderTabBar()
+
+	if current := event.Session.CurrentTab; current >= 0 && current < len(app.tabs) {
+		app.activateTab(app.tabs[current].id)
+	}
+}
+
+func (app *App) handleEventSaveSession() {
+	if app.cfg.Session.File == "" {
+		return
+	}
+	if err := app.saveSession(); err != nil {
+		app.Log().Error(errors.WithMessage(err, "save session"))
+	}
 }
 
 func (app *App) handleKeyCtrlC(_ *tcell.EventKey) *tcell.EventKey {
 	app.Log().Debug("Interrupting latest command")
-	app.Events().Dispatch(EventInterrupt{})
+	app.currentScope().Dispatch(EventInterrupt{})
 	return nil
 }
 
@@ -109,6 +290,95 @@ func (app *App) handleKeyEscape(event *tcell.EventKey) *tcell.EventKey {
 	return event
 }
 
+func (app *App) handleKeyRecord(_ *tcell.EventKey) *tcell.EventKey {
+	if app.recording != nil {
+		app.stopRecording()
+	} else if err := app.startRecording(); err != nil {
+		app.Log().Error(err)
+	}
+	return nil
+}
+
+func (app *App) handleKeyFocusNext(_ *tcell.EventKey) *tcell.EventKey {
+	app.cycleFocus(1)
+	return nil
+}
+
+func (app *App) handleKeyFocusPrev(_ *tcell.EventKey) *tcell.EventKey {
+	app.cycleFocus(-1)
+	return nil
+}
+
+func (app *App) handleKeyNewTab(_ *tcell.EventKey) *tcell.EventKey {
+	app.Events().Dispatch(EventAddTab{})
+	return nil
+}
+
+func (app *App) handleKeyCloseTab(_ *tcell.EventKey) *tcell.EventKey {
+	app.Events().Dispatch(EventRemoveTab{TabId: app.currentTab})
+	return nil
+}
+
+func (app *App) handleKeyRenameTab(_ *tcell.EventKey) *tcell.EventKey {
+	tabId := app.currentTab
+	app.Events().Dispatch(EventOpenDialog{Dialog: dialog.Input{
+		Title: "Rename tab",
+		Label: "Title",
+		OnOk: func(val string) {
+			if val = strings.TrimSpace(val); val != "" {
+				app.Events().Dispatch(EventRenameTab{TabId: tabId, Title: val})
+			}
+		},
+		Log: app.Log(),
+	}})
+	return nil
+}
+
+func (app *App) handleKeyNextTab(_ *tcell.EventKey) *tcell.EventKey {
+	app.switchTab(1)
+	return nil
+}
+
+func (app *App) handleKeyPrevTab(_ *tcell.EventKey) *tcell.EventKey {
+	app.switchTab(-1)
+	return nil
+}
+
+func (app *App) handleKeySplitRight(_ *tcell.EventKey) *tcell.EventKey {
+	app.Events().Dispatch(EventSplitPane{Direction: SplitRight})
+	return nil
+}
+
+func (app *App) handleKeySplitDown(_ *tcell.EventKey) *tcell.EventKey {
+	app.Events().Dispatch(EventSplitPane{Direction: SplitDown})
+	return nil
+}
+
+func (app *App) handleKeyClosePane(_ *tcell.EventKey) *tcell.EventKey {
+	app.Events().Dispatch(EventClosePane{})
+	return nil
+}
+
+func (app *App) handleKeyNextPane(_ *tcell.EventKey) *tcell.EventKey {
+	app.Events().Dispatch(EventFocusPane{Step: 1})
+	return nil
+}
+
+func (app *App) handleKeyPrevPane(_ *tcell.EventKey) *tcell.EventKey {
+	app.Events().Dispatch(EventFocusPane{Step: -1})
+	return nil
+}
+
+func (app *App) handleKeyGrowPane(_ *tcell.EventKey) *tcell.EventKey {
+	app.Events().Dispatch(EventResizePane{Delta: 1})
+	return nil
+}
+
+func (app *App) handleKeyShrinkPane(_ *tcell.EventKey) *tcell.EventKey {
+	app.Events().Dispatch(EventResizePane{Delta: -1})
+	return nil
+}
+
 func (app *App) handleKeyExit(_ *tcell.EventKey) *tcell.EventKey {
 	app.Events().Dispatch(EventExit{})
 	return nil
@@ -11,6 +11,11 @@ type ModuleConfig struct {
 	Enabled  bool       `json:"enabled"`
 	Focused  bool       `json:"focused"`
 	FocusKey config.Key `json:"focus_key"`
+	// Focusable defines whether the module is a part of the focus ring
+	Focusable bool `json:"focusable"`
+	// Pane defines whether the module gets its own instance in every pane of the tab.
+	// All pane modules are placed within the area of the grid, which they occupy together.
+	Pane bool `json:"pane"`
 }
 
 type Position struct {
@@ -23,7 +28,8 @@ type Position struct {
 // ------------------------------------------------------------ //
 
 var defaultModConfig = ModuleConfig{
-	Enabled: true,
+	Enabled:   true,
+	Focusable: true,
 	Position: Position{
 		Width:  10,
 		Height: 10,
@@ -38,6 +44,9 @@ type Module interface {
 	Init(Context) error
 }
 
+// ModuleFactory creates a new instance of the module.
+type ModuleFactory func() Module
+
 type ModuleView interface {
 	tview.Primitive
 	tview.Boxed
@@ -1,6 +1,7 @@
 package ext
 
 import (
+	"context"
 	"github.com/jumale/gooster/pkg/completion"
 	"github.com/jumale/gooster/pkg/events"
 	"github.com/jumale/gooster/pkg/gooster"
@@ -38,26 +39,14 @@ func (ext *BashCompletion) Init(_ gooster.Module, ctx gooster.Context) error {
 	}
 	ext.completer = completion.NewBashCompleter(ext.cfg.Completer)
 
-	ctx.Events().Subscribe(events.HandleWithPrio(10, func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case gooster.EventSetCompletion:
-			// skip if command already has completions defined by someone else
-			if len(event.Completion.Suggested) > 0 {
-				return e
-			}
-
-			if len(event.Commands) == 0 {
-				return e
-			}
-
-			var err error
-			if event.Completion, err = ext.completer.Get(event.Commands[len(event.Commands)-1]); err != nil {
-				ctx.Log().Debug(err)
-			}
-			return event
-		}
-		return e
-	}))
-
+	ctx.Events().Subscribe(events.Respond(ext.handleQueryCompletion))
 	return nil
 }
+
+func (ext *BashCompletion) handleQueryCompletion(_ context.Context, query gooster.QueryCompletion) (completion.Completion, error) {
+	if len(query.Commands) == 0 {
+		return completion.Completion{}, nil
+	}
+	// complete only the latest command
+	return ext.completer.InDir(query.WorkDir).Get(query.Commands[len(query.Commands)-1])
+}
@@ -9,45 +9,33 @@ import (
 )
 
 func TestExtension(t *testing.T) {
-	t.Run("should add completion to the event", func(t *testing.T) {
+	t.Run("should respond with completion of the command", func(t *testing.T) {
 		ext := tools.NewExtensionTester(t, NewBashCompletion(), nil, nil)
 		ext.AssertInited()
 
-		commands := []command.Definition{{Command: "comple"}}
-		ext.SendEvent(gooster.EventSetCompletion{Commands: commands})
-
-		ext.AssertFinalEvent(gooster.EventSetCompletion{
-			Commands:   commands,
-			Completion: completion.Completion{Suggested: []string{"complete"}},
-		})
+		ext.AssertResponse(
+			gooster.QueryCompletion{Commands: []command.Definition{{Command: "comple"}}},
+			completion.Completion{Suggested: []string{"complete"}},
+		)
 	})
 
-	t.Run("should not add/modify completion if the event already has non-empty completion", func(t *testing.T) {
+	t.Run("should not respond if there are no commands", func(t *testing.T) {
 		ext := tools.NewExtensionTester(t, NewBashCompletion(), nil, nil)
 		ext.AssertInited()
 
-		originalEvent := gooster.EventSetCompletion{
-			Commands:   []command.Definition{{Command: "comple"}},
-			Completion: completion.Completion{Suggested: []string{"bar", "baz"}},
-		}
-
-		ext.SendEvent(originalEvent)
-		ext.AssertFinalEvent(originalEvent)
+		ext.AssertResponse(gooster.QueryCompletion{}, nil)
 	})
 
 	t.Run("should complete only the latest command", func(t *testing.T) {
 		ext := tools.NewExtensionTester(t, NewBashCompletion(), nil, nil)
 		ext.AssertInited()
 
-		commands := []command.Definition{
-			{Command: "compge"},
-			{Command: "comple"},
-		}
-		ext.SendEvent(gooster.EventSetCompletion{Commands: commands})
-
-		ext.AssertFinalEvent(gooster.EventSetCompletion{
-			Commands:   commands,
-			Completion: completion.Completion{Suggested: []string{"complete"}},
-		})
+		ext.AssertResponse(
+			gooster.QueryCompletion{Commands: []command.Definition{
+				{Command: "compge"},
+				{Command: "comple"},
+			}},
+			completion.Completion{Suggested: []string{"complete"}},
+		)
 	})
 }
@@ -60,13 +60,7 @@ func (m *Module) Init(ctx gooster.Context) (err error) {
 	m.view.SetBackgroundColor(m.cfg.Colors.Bg.Origin())
 	m.view.SetSelectable(true, true)
 
-	m.Events().Subscribe(events.HandleFunc(func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case gooster.EventSetCompletion:
-			m.handleSetCompletion(event)
-		}
-		return e
-	}))
+	m.Events().Subscribe(events.On(m.handleSetCompletion))
 
 	gooster.HandleKeyEvents(m.view, gooster.KeyEventHandlers{
 		m.cfg.Keys.NextItem: m.handleNextItem,
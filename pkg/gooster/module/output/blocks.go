@@ -0,0 +1,429 @@
+package output
+
+import (
+	"fmt"
+	"github.com/jumale/gooster/pkg/ansi"
+	"github.com/rivo/tview"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Block is a part of the output, printed by a single command.
+// The output, printed outside of commands (e.g. logs), is stored in blocks without a command.
+type Block struct {
+	ID         int
+	Cmd        string
+	ExitCode   int
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Background tells that the command has been moved to background,
+	// so the block has been closed before the command has finished.
+	Background bool
+	Collapsed  bool
+	term       *ansi.Terminal
+	writer     io.Writer
+	// size of the block, as it's counted in the totals of the list
+	lines int
+	bytes int
+}
+
+func (b *Block) IsCommand() bool {
+	return b.Cmd != ""
+}
+
+func (b *Block) Finished() bool {
+	return !b.FinishedAt.IsZero()
+}
+
+// Text returns the block output without colors.
+func (b *Block) Text() string {
+	return b.term.Text()
+}
+
+func (b *Block) regionId() string {
+	return fmt.Sprintf("block_%d", b.ID)
+}
+
+// blockList keeps all blocks of the output in the order they have been printed.
+// The output is always written to the latest block.
+// When the scrollback limit is exceeded, the oldest lines are evicted to the spill file (if any).
+type blockList struct {
+	mu        *sync.Mutex
+	blocks    []*Block
+	lastID    int
+	height    int
+	writerCfg ansi.WriterConfig
+	maxLines  int
+	maxBytes  int
+	spill     *spillFile
+	// the evicted lines, which have been paged back in
+	restored string
+	// number of lines and bytes of all blocks,
+	// only the latest block receives the output, so only its size is counted again on write
+	lines int
+	bytes int
+}
+
+func newBlockList(writerCfg ansi.WriterConfig) *blockList {
+	l := &blockList{mu: &sync.Mutex{}, writerCfg: writerCfg}
+	l.add("")
+	return l
+}
+
+// open starts a new command block, which receives all further output.
+func (l *blockList) open(cmd string) *Block {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.finish(0)
+	return l.add(cmd)
+}
+
+// close finishes the current command block, all further output goes to a new block without command.
+func (l *blockList) close(exitCode int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.finish(exitCode) {
+		l.add("")
+	}
+}
+
+// detach closes the current command block without finishing it, since its command keeps running in background.
+// All further output goes to a new block without command.
+func (l *blockList) detach() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.detachLocked() {
+		l.add("")
+	}
+}
+
+func (l *blockList) detachLocked() bool {
+	curr := l.blocks[len(l.blocks)-1]
+	if !curr.IsCommand() || curr.Finished() || curr.Background {
+		return false
+	}
+	curr.Background = true
+	return true
+}
+
+// setLimit configures the scrollback limits, zero value means no limit.
+// The evicted lines are stored in the spill file, or dropped if the file is nil.
+func (l *blockList) setLimit(maxLines, maxBytes int, spill *spillFile) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxLines, l.maxBytes, l.spill = maxLines, maxBytes, spill
+}
+
+// write adds the data to the latest block, it returns number of the evicted lines.
+func (l *blockList) write(data []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.blocks[len(l.blocks)-1].writer.Write(data); err != nil {
+		return 0, err
+	}
+	l.count()
+	return l.trim()
+}
+
+// loadOlder pages back in up to n evicted lines, it returns number of the loaded lines.
+func (l *blockList) loadOlder(n int) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.spill == nil {
+		return 0, nil
+	}
+	remaining := l.spill.remaining()
+	text, err := l.spill.page(n)
+	if err != nil {
+		return 0, err
+	}
+	l.restored = text + l.restored
+	return remaining - l.spill.remaining(), nil
+}
+
+// unload drops the paged in lines from the output, so that they don't grow it beyond the limit.
+func (l *blockList) unload() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.unloadLocked()
+}
+
+func (l *blockList) unloadLocked() {
+	l.restored = ""
+	if l.spill != nil {
+		l.spill.rewind()
+	}
+}
+
+// savedBlock is a block, which is kept in the session.
+type savedBlock struct {
+	Cmd        string `json:"cmd,omitempty"`
+	ExitCode   int    `json:"exit_code,omitempty"`
+	Background bool   `json:"background,omitempty"`
+	Text       string `json:"text"`
+}
+
+func (b savedBlock) IsCommand() bool {
+	return b.Cmd != ""
+}
+
+// save returns all blocks without colors.
+func (l *blockList) save() []savedBlock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var saved []savedBlock
+	for _, block := range l.blocks {
+		saved = append(saved, savedBlock{
+			Cmd:        block.Cmd,
+			ExitCode:   block.ExitCode,
+			Background: block.Background,
+			Text:       block.Text(),
+		})
+	}
+	return saved
+}
+
+// restore adds the saved blocks to the output, it returns number of the evicted lines.
+func (l *blockList) restore(saved []savedBlock) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, block := range saved {
+		if l.finish(0) || block.IsCommand() {
+			l.add(block.Cmd)
+		}
+		if _, err := l.blocks[len(l.blocks)-1].writer.Write([]byte(block.Text)); err != nil {
+			return 0, err
+		}
+		l.count()
+		closed := false
+		if block.Background {
+			closed = l.detachLocked()
+		} else {
+			closed = l.finish(block.ExitCode)
+		}
+		if closed {
+			l.add("")
+		}
+	}
+	return l.trim()
+}
+
+// release closes the spill file.
+func (l *blockList) release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.spill == nil {
+		return nil
+	}
+	err := l.spill.close()
+	l.spill = nil
+	return err
+}
+
+// toggle collapses or expands the block, it returns false if the block is not found.
+func (l *blockList) toggle(id int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, block := range l.blocks {
+		if block.ID == id && block.IsCommand() {
+			block.Collapsed = !block.Collapsed
+			return true
+		}
+	}
+	return false
+}
+
+func (l *blockList) setHeight(height int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.height = height
+	l.blocks[len(l.blocks)-1].term.SetHeight(height)
+}
+
+func (l *blockList) get(id int) *Block {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, block := range l.blocks {
+		if block.ID == id {
+			return block
+		}
+	}
+	return nil
+}
+
+// prev returns the command block before the one with the provided ID,
+// or the last command block if the ID is not found.
+func (l *blockList) prev(id int) *Block {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var found *Block
+	for _, block := range l.blocks {
+		if block.ID == id {
+			if found == nil {
+				return block
+			}
+			return found
+		}
+		if block.IsCommand() {
+			found = block
+		}
+	}
+	return found
+}
+
+// next returns the command block after the one with the provided ID,
+// or nil if there are no more command blocks.
+func (l *blockList) next(id int) *Block {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	passed := false
+	for _, block := range l.blocks {
+		if passed && block.IsCommand() {
+			return block
+		}
+		if block.ID == id {
+			passed = true
+		}
+	}
+	return nil
+}
+
+type renderConfig struct {
+	collapsedColor string
+	loadOlderKey   string
+	matchColor     string
+	matches        []Match
+}
+
+// render returns the whole output as a text with tview color tags,
+// every command block is wrapped in its own region.
+func (l *blockList) render(cfg renderConfig) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var buf strings.Builder
+	if l.spill != nil && l.spill.remaining() > 0 {
+		_, _ = fmt.Fprintf(&buf, "[%s]▴ %d earlier lines are hidden, press %s to load them[-]\n",
+			cfg.collapsedColor, l.spill.remaining(), tview.Escape(cfg.loadOlderKey))
+	}
+	buf.WriteString(l.restored)
+
+	for _, block := range l.blocks {
+		var marks []ansi.Mark
+		if len(cfg.matches) > 0 {
+			marks = blockMarks(block, cfg.matches, cfg.matchColor)
+		}
+
+		if !block.IsCommand() {
+			buf.WriteString(block.term.Render(marks))
+			continue
+		}
+
+		buf.WriteString(`["` + block.regionId() + `"][-:-:-]`)
+		if block.Collapsed {
+			_, _ = fmt.Fprintf(&buf, "[%s]▸ %s (%d lines)[-]\n", cfg.collapsedColor, tview.Escape(block.Cmd), block.term.Lines())
+		} else {
+			buf.WriteString(block.term.Render(marks))
+		}
+		buf.WriteString(`[-:-:-][""]`)
+	}
+	return buf.String()
+}
+
+func (l *blockList) add(cmd string) *Block {
+	l.lastID++
+	term := ansi.NewTerminal()
+	term.SetHeight(l.height)
+	block := &Block{
+		ID:        l.lastID,
+		Cmd:       cmd,
+		StartedAt: time.Now(),
+		term:      term,
+		writer:    ansi.NewWriter(term, l.writerCfg),
+	}
+	l.blocks = append(l.blocks, block)
+	return block
+}
+
+// finish marks the current command block as finished,
+// it returns false if the current block is not an open command block.
+func (l *blockList) finish(exitCode int) bool {
+	curr := l.blocks[len(l.blocks)-1]
+	if !curr.IsCommand() || curr.Finished() || curr.Background {
+		return false
+	}
+	curr.ExitCode = exitCode
+	curr.FinishedAt = time.Now()
+	return true
+}
+
+// count updates the totals by the changed size of the latest block.
+func (l *blockList) count() {
+	curr := l.blocks[len(l.blocks)-1]
+	lines, bytes := curr.term.Size()
+	l.lines += lines - curr.lines
+	l.bytes += bytes - curr.bytes
+	curr.lines, curr.bytes = lines, bytes
+}
+
+func (l *blockList) exceeds(lines, bytes int) bool {
+	return (l.maxLines > 0 && lines > l.maxLines) || (l.maxBytes > 0 && bytes > l.maxBytes)
+}
+
+// trim evicts the oldest lines, until the output fits the scrollback limits.
+// It returns number of the evicted lines.
+func (l *blockList) trim() (int, error) {
+	var evicted []string
+	for l.exceeds(l.lines, l.bytes) {
+		oldest := l.blocks[0]
+		if len(l.blocks) > 1 {
+			evicted = append(evicted, oldest.term.TrimTop(oldest.lines)...)
+			l.lines -= oldest.lines
+			l.bytes -= oldest.bytes
+			l.blocks = l.blocks[1:]
+			continue
+		}
+
+		// the latest block receives the output, so only its oldest lines are evicted
+		n, lines, bytes := 0, l.lines, l.bytes
+		for n < oldest.lines && l.exceeds(lines, bytes) {
+			lines--
+			bytes -= oldest.term.LineSize(n) + 1
+			n++
+		}
+		evicted = append(evicted, oldest.term.TrimTop(n)...)
+		l.count()
+		break
+	}
+
+	if len(evicted) > 0 {
+		// the paged in lines would be separated from the output by the evicted ones, so they are dropped
+		l.unloadLocked()
+	}
+	if l.spill != nil {
+		if err := l.spill.append(evicted); err != nil {
+			// the output must not stop, so the further lines are just dropped
+			_ = l.spill.close()
+			l.spill = nil
+			return len(evicted), err
+		}
+	}
+	return len(evicted), nil
+}
@@ -0,0 +1,198 @@
+package output
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/ansi"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBlockList(t *testing.T) {
+	assert := require.New(t)
+	newList := func() *blockList {
+		return newBlockList(ansi.WriterConfig{DefaultFg: tcell.ColorDefault, DefaultBg: tcell.ColorDefault})
+	}
+	write := func(l *blockList, data string) int {
+		evicted, err := l.write([]byte(data))
+		assert.NoError(err)
+		return evicted
+	}
+
+	t.Run("should store output of commands in separate blocks", func(t *testing.T) {
+		l := newList()
+		write(l, "> ls\n")
+		foo := l.open("ls")
+		write(l, "foo\nbar\n")
+		l.close(2)
+		write(l, "---\n")
+
+		assert.Equal("foo\nbar\n", foo.Text())
+		assert.Equal(2, foo.ExitCode)
+		assert.True(foo.Finished())
+		assert.Equal(
+			"> ls\n"+`["block_2"][-:-:-]foo`+"\n"+`bar`+"\n"+`[-:-:-][""]---`+"\n",
+			l.render(renderConfig{collapsedColor: "gray"}),
+		)
+	})
+
+	t.Run("should render collapsed blocks", func(t *testing.T) {
+		l := newList()
+		block := l.open("ls")
+		write(l, "foo\nbar\n")
+		l.close(0)
+
+		assert.True(l.toggle(block.ID))
+		assert.Equal(`["block_2"][-:-:-][gray]▸ ls (2 lines)[-]`+"\n"+`[-:-:-][""]`, l.render(renderConfig{collapsedColor: "gray"}))
+		assert.False(l.toggle(1), "should not toggle blocks without command")
+	})
+
+	t.Run("should navigate between command blocks", func(t *testing.T) {
+		l := newList()
+		first := l.open("foo")
+		l.close(0)
+		write(l, "log message\n")
+		second := l.open("bar")
+		l.close(0)
+
+		assert.Equal(second, l.prev(0))
+		assert.Equal(first, l.prev(second.ID))
+		assert.Equal(first, l.prev(first.ID))
+		assert.Equal(second, l.next(first.ID))
+		assert.Nil(l.next(second.ID))
+	})
+
+	t.Run("should finish the previous command block when a new one is opened", func(t *testing.T) {
+		l := newList()
+		first := l.open("foo")
+		l.open("bar")
+		assert.True(first.Finished())
+	})
+
+	t.Run("should close the block of a backgrounded command without finishing it", func(t *testing.T) {
+		l := newList()
+		block := l.open("sleep 10")
+		write(l, "foo\n")
+		l.detach()
+		write(l, "---\n")
+		l.open("ls")
+
+		assert.True(block.Background)
+		assert.False(block.Finished())
+		assert.Equal("foo\n", block.Text())
+
+		restored := newList()
+		_, err := restored.restore(l.save())
+		assert.NoError(err)
+		saved := restored.prev(restored.prev(0).ID)
+		assert.Equal("sleep 10", saved.Cmd)
+		assert.True(saved.Background)
+		assert.False(saved.Finished())
+	})
+
+	t.Run("should restore the saved blocks", func(t *testing.T) {
+		l := newList()
+		write(l, "log\n")
+		l.open("ls")
+		write(l, "foo\nbar\n")
+		l.close(2)
+
+		restored := newList()
+		_, err := restored.restore(l.save())
+		assert.NoError(err)
+		write(restored, "---\n")
+
+		block := restored.prev(0)
+		assert.Equal("ls", block.Cmd)
+		assert.Equal(2, block.ExitCode)
+		assert.Equal("foo\nbar\n", block.Text())
+		assert.Equal(
+			"log\n"+`["block_2"][-:-:-]foo`+"\n"+`bar`+"\n"+`[-:-:-][""]---`+"\n",
+			restored.render(renderConfig{}),
+		)
+	})
+
+	t.Run("should evict the oldest lines beyond the limit", func(t *testing.T) {
+		l := newList()
+		l.setLimit(3, 0, nil)
+		write(l, "log\n")
+		l.open("ls")
+		write(l, "foo\nbar\n")
+		l.close(0)
+
+		assert.Equal(1, write(l, "baz"))
+		assert.Nil(l.get(1), "should remove the evicted block")
+		assert.Equal(0, write(l, "\n"))
+		assert.Equal(2, write(l, "qux\n"))
+		assert.Equal("baz\nqux\n", l.render(renderConfig{}))
+	})
+
+	t.Run("should evict the oldest lines beyond the size limit", func(t *testing.T) {
+		l := newList()
+		l.setLimit(0, 8, nil)
+		assert.Equal(1, write(l, "foo\nbär\n"))
+		assert.Equal("bär\n", l.render(renderConfig{}))
+	})
+
+	t.Run("should count the rewritten lines of the latest block", func(t *testing.T) {
+		l := newList()
+		l.setLimit(0, 12, nil)
+		assert.Equal(0, write(l, "foo\nbarbaz"))
+		assert.Equal(0, write(l, "\r\033[Kbar\n"))
+		assert.Equal(1, write(l, "quux\n"))
+		assert.Equal("bar\nquux\n", l.render(renderConfig{}))
+	})
+
+	t.Run("should page the evicted lines back in", func(t *testing.T) {
+		spill, err := newSpillFile("")
+		assert.NoError(err)
+		l := newList()
+		l.setLimit(1, 0, spill)
+		defer func() { assert.NoError(l.release()) }()
+		write(l, "[red]foo[-]\nbar\nbaz\n")
+
+		cfg := renderConfig{collapsedColor: "gray", loadOlderKey: "o"}
+		assert.Equal("[gray]▴ 2 earlier lines are hidden, press o to load them[-]\nbaz\n", l.render(cfg))
+
+		loaded, err := l.loadOlder(1)
+		assert.NoError(err)
+		assert.Equal(1, loaded)
+		assert.Equal("[gray]▴ 1 earlier lines are hidden, press o to load them[-]\nbar\nbaz\n", l.render(cfg))
+
+		loaded, err = l.loadOlder(5)
+		assert.NoError(err)
+		assert.Equal(1, loaded)
+		assert.Equal("[red:-:-]foo[-:-:-]\nbar\nbaz\n", l.render(cfg))
+
+		l.unload()
+		assert.Equal("[gray]▴ 2 earlier lines are hidden, press o to load them[-]\nbaz\n", l.render(cfg))
+	})
+
+	t.Run("should page in the lines evicted after the previous page in", func(t *testing.T) {
+		spill, err := newSpillFile("")
+		assert.NoError(err)
+		l := newList()
+		l.setLimit(1, 0, spill)
+		defer func() { assert.NoError(l.release()) }()
+		write(l, "foo\nbar\n")
+
+		cfg := renderConfig{collapsedColor: "gray", loadOlderKey: "o"}
+		loaded, err := l.loadOlder(1)
+		assert.NoError(err)
+		assert.Equal(1, loaded)
+		assert.Equal("foo\nbar\n", l.render(cfg))
+
+		// the new output evicts more lines and drops the paged in ones
+		write(l, "baz\nqux\n")
+		assert.Equal("[gray]▴ 3 earlier lines are hidden, press o to load them[-]\nqux\n", l.render(cfg))
+
+		loaded, err = l.loadOlder(2)
+		assert.NoError(err)
+		assert.Equal(2, loaded)
+		assert.Equal("[gray]▴ 1 earlier lines are hidden, press o to load them[-]\nbar\nbaz\nqux\n", l.render(cfg))
+
+		loaded, err = l.loadOlder(2)
+		assert.NoError(err)
+		assert.Equal(1, loaded)
+		assert.Equal("foo\nbar\nbaz\nqux\n", l.render(cfg))
+	})
+}
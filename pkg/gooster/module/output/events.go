@@ -0,0 +1,106 @@
+package output
+
+// EventResize is dispatched when the inner area of the output view
+// has changed its size (e.g. when the terminal window has been resized).
+type EventResize struct {
+	Width  int
+	Height int
+}
+
+// EventOpenBlock starts a new output block of the command,
+// all further output is stored in this block until it's closed.
+type EventOpenBlock struct {
+	Cmd string
+}
+
+// EventCloseBlock finishes the current command block.
+type EventCloseBlock struct {
+	ExitCode int
+	// Background tells that the command has been moved to background,
+	// so the block is closed without marking it as finished.
+	Background bool
+}
+
+// EventSelectBlock highlights the command block. Zero ID resets the selection.
+type EventSelectBlock struct {
+	ID int
+}
+
+func (e EventSelectBlock) NeedsDraw() bool {
+	return true
+}
+
+// EventToggleBlock collapses or expands the command block.
+type EventToggleBlock struct {
+	ID int
+}
+
+func (e EventToggleBlock) NeedsDraw() bool {
+	return true
+}
+
+// EventSaveBlock writes output of the block to the file.
+type EventSaveBlock struct {
+	ID   int
+	Path string
+}
+
+// EventCopyBlock is dispatched when the output of a block should be copied to the prompt.
+type EventCopyBlock struct {
+	Text string
+}
+
+// EventRerunBlock is dispatched when the command of a block should be executed again.
+type EventRerunBlock struct {
+	Cmd string
+}
+
+// EventLoadOlder pages back in the output lines, which have been evicted from the scrollback.
+type EventLoadOlder struct{}
+
+func (e EventLoadOlder) NeedsDraw() bool {
+	return true
+}
+
+// EventOpenSearch shows the search field.
+type EventOpenSearch struct{}
+
+func (e EventOpenSearch) NeedsDraw() bool {
+	return true
+}
+
+// EventCloseSearch hides the search field.
+// Found matches stay highlighted, unless Reset is set.
+type EventCloseSearch struct {
+	Reset bool
+}
+
+func (e EventCloseSearch) NeedsDraw() bool {
+	return true
+}
+
+// EventSearch finds all matches of the query in the output and jumps to the first one.
+type EventSearch struct {
+	Options SearchOptions
+}
+
+func (e EventSearch) NeedsDraw() bool {
+	return true
+}
+
+// EventSelectMatch jumps to the found match (by its index in the list of matches).
+type EventSelectMatch struct {
+	Index int
+}
+
+func (e EventSelectMatch) NeedsDraw() bool {
+	return true
+}
+
+// EventSearchUpdated is dispatched when the search results or the current match have changed.
+type EventSearchUpdated struct {
+	Query   string
+	Current int // 1-based number of the current match, 0 if there are no matches
+	Total   int
+	Active  bool
+}
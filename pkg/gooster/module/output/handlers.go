@@ -0,0 +1,384 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/dialog"
+	"github.com/jumale/gooster/pkg/gooster"
+	"github.com/pkg/errors"
+	"io"
+	"strings"
+)
+
+func (m *Module) handleEventOutput(event gooster.EventOutput) {
+	evicted, err := m.blocks.write(event.Data)
+	if err != nil {
+		m.Log().Error(errors.WithMessage(err, "write to output"))
+	}
+	if evicted > 0 && len(m.matches) > 0 {
+		// positions of the matches in the trimmed block are not valid anymore
+		m.refreshMatches()
+	}
+	m.renderOutput()
+}
+
+func (m *Module) handleEventExit() {
+	if err := m.blocks.release(); err != nil {
+		m.Log().Error(errors.WithMessage(err, "remove output spill file"))
+	}
+}
+
+func (m *Module) handleEventRestoreState(event gooster.EventRestoreState) {
+	if !m.cfg.Scrollback.Restore {
+		return
+	}
+	var saved []savedBlock
+	if ok, err := event.Modules.Load(m.Name(), &saved); err != nil || !ok {
+		m.Log().Check(err)
+		return
+	}
+	if _, err := m.blocks.restore(saved); err != nil {
+		m.Log().Error(errors.WithMessage(err, "restore output"))
+	}
+	m.render()
+}
+
+func (m *Module) handleQuerySessionState(_ context.Context, _ gooster.QuerySessionState) (gooster.ModuleStates, error) {
+	if !m.cfg.Scrollback.Restore {
+		return nil, nil
+	}
+	return gooster.NewModuleState(m.Name(), m.blocks.save())
+}
+
+func (m *Module) handleEventOpenBlock(event EventOpenBlock) {
+	// a new command brings the view back to the latest output, so the loaded lines are not needed anymore
+	m.blocks.unload()
+	m.blocks.open(event.Cmd)
+	m.render()
+}
+
+func (m *Module) handleEventLoadOlder() {
+	loaded, err := m.blocks.loadOlder(m.cfg.Scrollback.PageLines)
+	if err != nil {
+		m.Log().Error(errors.WithMessage(err, "load older output"))
+		return
+	}
+	if loaded == 0 {
+		m.Log().Info("There is no older output")
+		return
+	}
+	m.render()
+	m.view.ScrollToBeginning()
+}
+
+func (m *Module) handleEventCloseBlock(event EventCloseBlock) {
+	if event.Background {
+		m.blocks.detach()
+	} else {
+		m.blocks.close(event.ExitCode)
+	}
+	m.render()
+}
+
+func (m *Module) handleEventSelectBlock(event EventSelectBlock) {
+	m.selected = event.ID
+	if event.ID == 0 {
+		m.view.Highlight()
+		m.view.ScrollToEnd()
+		return
+	}
+
+	block := m.blocks.get(event.ID)
+	if block == nil {
+		m.Log().ErrorF("Could not select output block %d. Not found.", event.ID)
+		return
+	}
+	m.view.Highlight(block.regionId())
+	m.view.ScrollToHighlight()
+}
+
+func (m *Module) handleEventToggleBlock(event EventToggleBlock) {
+	if !m.blocks.toggle(event.ID) {
+		m.Log().ErrorF("Could not toggle output block %d. Not found.", event.ID)
+		return
+	}
+	m.render()
+}
+
+func (m *Module) handleEventSaveBlock(event EventSaveBlock) {
+	block := m.blocks.get(event.ID)
+	if block == nil {
+		m.Log().ErrorF("Could not save output block %d. Not found.", event.ID)
+		return
+	}
+
+	file, err := m.Fs().Create(event.Path)
+	if err != nil {
+		m.Log().Error(errors.WithMessage(err, "save output block"))
+		return
+	}
+	defer file.Close()
+
+	if _, err = io.WriteString(file, block.Text()); err != nil {
+		m.Log().Error(errors.WithMessage(err, "save output block"))
+		return
+	}
+	m.Log().InfoF("Output of `%s` is saved to %s", block.Cmd, event.Path)
+}
+
+func (m *Module) handleEventOpenSearch() {
+	// the field is removed first, in case if the search is already open
+	m.layout.RemoveItem(m.searchField)
+	m.layout.AddItem(m.searchField, 1, 0, false)
+	m.Events().Dispatch(gooster.EventSetFocus{Target: m.searchField})
+	if m.search.Query != "" {
+		m.Events().Dispatch(EventSearch{Options: m.search})
+	}
+}
+
+func (m *Module) handleEventCloseSearch(event EventCloseSearch) {
+	m.layout.RemoveItem(m.searchField)
+	m.Events().Dispatch(gooster.EventSetFocus{Target: m.view})
+	if !event.Reset {
+		return
+	}
+
+	m.mu.Lock()
+	m.matches = nil
+	m.mu.Unlock()
+	m.render()
+
+	if block := m.selectedBlock(); block != nil {
+		m.view.Highlight(block.regionId())
+	} else {
+		m.view.Highlight()
+	}
+	m.Events().Dispatch(EventSearchUpdated{Query: m.search.Query})
+}
+
+func (m *Module) handleEventSearch(event EventSearch) {
+	m.search = event.Options
+	m.updateSearchLabel()
+
+	matches, err := m.blocks.search(event.Options)
+	if err != nil {
+		// the pattern is usually invalid while it's being typed
+		m.Log().Debug(err)
+	}
+
+	m.mu.Lock()
+	m.matches = matches
+	m.mu.Unlock()
+	m.render()
+
+	if len(matches) == 0 {
+		m.view.Highlight()
+		m.Events().Dispatch(EventSearchUpdated{Query: event.Options.Query, Active: true})
+		return
+	}
+	// the latest output is the most relevant
+	m.Events().Dispatch(EventSelectMatch{Index: len(matches) - 1})
+}
+
+// refreshMatches searches the matches again, keeping the current one if it's still available.
+func (m *Module) refreshMatches() {
+	matches, err := m.blocks.search(m.search)
+	if err != nil {
+		m.Log().Debug(err)
+	}
+
+	m.mu.Lock()
+	removed := len(m.matches) - len(matches)
+	m.matches = matches
+	m.mu.Unlock()
+
+	if m.currMatch -= removed; m.currMatch >= len(matches) {
+		m.currMatch = len(matches) - 1
+	}
+	if m.currMatch < 0 {
+		m.currMatch = 0
+	}
+	current := 0
+	if len(matches) > 0 {
+		current = m.currMatch + 1
+		m.view.Highlight(Match{}.regionId(m.currMatch))
+	}
+	m.Events().Dispatch(EventSearchUpdated{
+		Query:   m.search.Query,
+		Current: current,
+		Total:   len(matches),
+		Active:  true,
+	})
+}
+
+func (m *Module) handleEventSelectMatch(event EventSelectMatch) {
+	if event.Index < 0 || event.Index >= len(m.matches) {
+		m.Log().ErrorF("Could not select match %d. Not found.", event.Index)
+		return
+	}
+
+	match := m.matches[event.Index]
+	if block := m.blocks.get(match.BlockID); block != nil && block.Collapsed {
+		m.blocks.toggle(block.ID)
+		m.render()
+	}
+
+	m.currMatch = event.Index
+	m.view.Highlight(match.regionId(event.Index))
+	m.view.ScrollToHighlight()
+	m.Events().Dispatch(EventSearchUpdated{
+		Query:   m.search.Query,
+		Current: event.Index + 1,
+		Total:   len(m.matches),
+		Active:  true,
+	})
+}
+
+func (m *Module) handleSearchDone(key tcell.Key) {
+	m.Events().Dispatch(EventCloseSearch{Reset: key == tcell.KeyEscape})
+}
+
+func (m *Module) handleKeySearch(event *tcell.EventKey) *tcell.EventKey {
+	m.Events().Dispatch(EventOpenSearch{})
+	return nil
+}
+
+func (m *Module) handleKeySearchNext(event *tcell.EventKey) *tcell.EventKey {
+	if len(m.matches) > 0 {
+		m.Events().Dispatch(EventSelectMatch{Index: (m.currMatch + 1) % len(m.matches)})
+	}
+	return nil
+}
+
+func (m *Module) handleKeySearchPrev(event *tcell.EventKey) *tcell.EventKey {
+	if len(m.matches) > 0 {
+		m.Events().Dispatch(EventSelectMatch{Index: (m.currMatch - 1 + len(m.matches)) % len(m.matches)})
+	}
+	return nil
+}
+
+func (m *Module) handleKeySearchRegex(event *tcell.EventKey) *tcell.EventKey {
+	opts := m.search
+	opts.Regex = !opts.Regex
+	m.Events().Dispatch(EventSearch{Options: opts})
+	return nil
+}
+
+func (m *Module) handleKeySearchCase(event *tcell.EventKey) *tcell.EventKey {
+	opts := m.search
+	opts.CaseSensitive = !opts.CaseSensitive
+	m.Events().Dispatch(EventSearch{Options: opts})
+	return nil
+}
+
+// handleKeySearchBlock limits the search to the selected command block (or the latest one, if none is selected).
+func (m *Module) handleKeySearchBlock(event *tcell.EventKey) *tcell.EventKey {
+	opts := m.search
+	if opts.BlockID != 0 {
+		opts.BlockID = 0
+	} else if block := m.selectedBlock(); block != nil {
+		opts.BlockID = block.ID
+	} else if block := m.blocks.prev(0); block != nil {
+		opts.BlockID = block.ID
+	} else {
+		m.Log().Info("There are no command blocks to search in")
+		return nil
+	}
+	m.Events().Dispatch(EventSearch{Options: opts})
+	return nil
+}
+
+func (m *Module) updateSearchLabel() {
+	var flags []string
+	if m.search.Regex {
+		flags = append(flags, "regex")
+	}
+	if m.search.CaseSensitive {
+		flags = append(flags, "case")
+	}
+	if m.search.BlockID != 0 {
+		flags = append(flags, "block")
+	}
+
+	label := "Search: "
+	if len(flags) > 0 {
+		label = fmt.Sprintf("Search (%s): ", strings.Join(flags, ", "))
+	}
+	m.searchField.SetLabel(label)
+}
+
+func (m *Module) handleKeyPrevBlock(event *tcell.EventKey) *tcell.EventKey {
+	if block := m.blocks.prev(m.selected); block != nil {
+		m.Events().Dispatch(EventSelectBlock{ID: block.ID})
+	}
+	return nil
+}
+
+func (m *Module) handleKeyNextBlock(event *tcell.EventKey) *tcell.EventKey {
+	if m.selected == 0 {
+		return nil
+	}
+	// the selection is reset after the last block
+	id := 0
+	if block := m.blocks.next(m.selected); block != nil {
+		id = block.ID
+	}
+	m.Events().Dispatch(EventSelectBlock{ID: id})
+	return nil
+}
+
+func (m *Module) handleKeyToggleBlock(event *tcell.EventKey) *tcell.EventKey {
+	if m.selected == 0 {
+		return event
+	}
+	m.Events().Dispatch(EventToggleBlock{ID: m.selected})
+	return nil
+}
+
+func (m *Module) handleKeyCopyBlock(event *tcell.EventKey) *tcell.EventKey {
+	block := m.selectedBlock()
+	if block == nil {
+		return event
+	}
+	m.Events().Dispatch(EventCopyBlock{Text: strings.TrimRight(block.Text(), "\n")})
+	return nil
+}
+
+func (m *Module) handleKeyRerunBlock(event *tcell.EventKey) *tcell.EventKey {
+	block := m.selectedBlock()
+	if block == nil {
+		return event
+	}
+	m.Events().Dispatch(EventRerunBlock{Cmd: block.Cmd})
+	return nil
+}
+
+func (m *Module) handleKeyLoadOlder(event *tcell.EventKey) *tcell.EventKey {
+	m.Events().Dispatch(EventLoadOlder{})
+	return nil
+}
+
+func (m *Module) handleKeySaveBlock(event *tcell.EventKey) *tcell.EventKey {
+	block := m.selectedBlock()
+	if block == nil {
+		return event
+	}
+	m.Events().Dispatch(gooster.EventOpenDialog{Dialog: dialog.Input{
+		Title: "Save output",
+		Label: "File Name",
+		Width: 40,
+		OnOk:  func(val string) { m.Events().Dispatch(EventSaveBlock{ID: block.ID, Path: val}) },
+		Log:   m.Log(),
+	}})
+	return nil
+}
+
+func (m *Module) handleKeyEscape(event *tcell.EventKey) *tcell.EventKey {
+	if len(m.matches) > 0 {
+		m.Events().Dispatch(EventCloseSearch{Reset: true})
+	} else if m.selected != 0 {
+		m.Events().Dispatch(EventSelectBlock{ID: 0})
+	}
+	return event
+}
@@ -1,35 +1,108 @@
 package output
 
 import (
+	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/jumale/gooster/pkg/ansi"
 	"github.com/jumale/gooster/pkg/config"
 	"github.com/jumale/gooster/pkg/events"
 	"github.com/jumale/gooster/pkg/gooster"
-	"github.com/pkg/errors"
 	"github.com/rivo/tview"
+	"sync"
+	"time"
 )
 
 type Config struct {
-	Colors ColorsConfig
+	Scrollback ScrollbackConfig `json:"scrollback"`
+	Colors     ColorsConfig     `json:"colors"`
+	Keys       KeysConfig       `json:"keys"`
+}
+
+type ScrollbackConfig struct {
+	// MaxLines and MaxBytes limit the output kept in the view, zero value means no limit.
+	MaxLines int `json:"max_lines"`
+	MaxBytes int `json:"max_bytes"`
+	// Spill stores the evicted lines in a temporary file (in SpillDir or in the system temp dir),
+	// so that they could be loaded back by PageLines at once. Otherwise the evicted lines are dropped.
+	Spill     bool   `json:"spill"`
+	SpillDir  string `json:"spill_dir"`
+	PageLines int    `json:"page_lines"`
+	// Restore keeps the output in the session, so that it's shown again after restart.
+	Restore bool `json:"restore"`
 }
 
 type ColorsConfig struct {
-	Bg   config.Color
-	Text config.Color
+	Bg        config.Color `json:"bg"`
+	Text      config.Color `json:"text"`
+	Collapsed config.Color `json:"collapsed"`
+	Match     config.Color `json:"match"`
+	SearchBg  config.Color `json:"search_bg"`
+}
+
+type KeysConfig struct {
+	PrevBlock   config.Key `json:"prev_block"`
+	NextBlock   config.Key `json:"next_block"`
+	ToggleBlock config.Key `json:"toggle_block"`
+	CopyBlock   config.Key `json:"copy_block"`
+	RerunBlock  config.Key `json:"rerun_block"`
+	SaveBlock   config.Key `json:"save_block"`
+	LoadOlder   config.Key `json:"load_older"`
+	Search      config.Key `json:"search"`
+	// the keys below are handled by the search field
+	SearchNext  config.Key `json:"search_next"`
+	SearchPrev  config.Key `json:"search_prev"`
+	SearchRegex config.Key `json:"search_regex"`
+	SearchCase  config.Key `json:"search_case"`
+	SearchBlock config.Key `json:"search_block"`
 }
 
 type Module struct {
 	gooster.Context
-	cfg  Config
-	view *tview.TextView
+	cfg         Config
+	layout      *tview.Flex
+	view        *tview.TextView
+	searchField *tview.InputField
+	blocks      *blockList
+	selected    int
+	search      SearchOptions
+	matches     []Match
+	currMatch   int
+	mu          *sync.Mutex
+	width       int
+	height      int
+	// renderedAt and renderQueued throttle the updates of the view by the output
+	renderedAt   time.Time
+	renderQueued bool
 }
 
 func NewModule() *Module {
-	return &Module{cfg: Config{
+	return &Module{mu: &sync.Mutex{}, cfg: Config{
+		Scrollback: ScrollbackConfig{
+			MaxLines:  10000,
+			Spill:     true,
+			PageLines: 1000,
+		},
 		Colors: ColorsConfig{
-			Bg:   config.Color(tcell.NewHexColor(0x222222)),
-			Text: config.Color(tcell.ColorDefault),
+			Bg:        config.Color(tcell.NewHexColor(0x222222)),
+			Text:      config.Color(tcell.ColorDefault),
+			Collapsed: config.Color(tcell.ColorDarkGray),
+			Match:     config.Color(tcell.ColorOlive),
+			SearchBg:  config.Color(tcell.NewHexColor(0x555555)),
+		},
+		Keys: KeysConfig{
+			PrevBlock:   config.NewKey(tcell.KeyRune).SetRune('p'),
+			NextBlock:   config.NewKey(tcell.KeyRune).SetRune('n'),
+			ToggleBlock: config.NewKey(tcell.KeyEnter),
+			CopyBlock:   config.NewKey(tcell.KeyRune).SetRune('y'),
+			RerunBlock:  config.NewKey(tcell.KeyRune).SetRune('r'),
+			SaveBlock:   config.NewKey(tcell.KeyRune).SetRune('s'),
+			LoadOlder:   config.NewKey(tcell.KeyRune).SetRune('o'),
+			Search:      config.NewKey(tcell.KeyRune).SetRune('/'),
+			SearchNext:  config.NewKey(tcell.KeyDown),
+			SearchPrev:  config.NewKey(tcell.KeyUp),
+			SearchRegex: config.NewKey(tcell.KeyCtrlR),
+			SearchCase:  config.NewKey(tcell.KeyCtrlT),
+			SearchBlock: config.NewKey(tcell.KeyCtrlB),
 		},
 	}}
 }
@@ -39,7 +112,7 @@ func (m *Module) Name() string {
 }
 
 func (m *Module) View() gooster.ModuleView {
-	return m.view
+	return m.layout
 }
 
 func (m *Module) Init(ctx gooster.Context) error {
@@ -49,27 +122,161 @@ func (m *Module) Init(ctx gooster.Context) error {
 	}
 
 	m.view = tview.NewTextView()
-	output := ansi.NewWriter(m.view, ansi.WriterConfig{
+	m.blocks = newBlockList(ansi.WriterConfig{
 		DefaultFg: m.cfg.Colors.Text.Origin(),
 		DefaultBg: m.cfg.Colors.Bg.Origin(),
 	})
+	if err := m.initScrollback(); err != nil {
+		return err
+	}
 
 	m.view.SetBorder(false)
 	m.view.SetDynamicColors(true)
+	m.view.SetRegions(true)
 	m.view.SetScrollable(true)
 	m.view.SetBorderPadding(0, 0, 1, 1)
 	m.view.SetBackgroundColor(m.cfg.Colors.Bg.Origin())
 	m.view.SetTextColor(m.cfg.Colors.Text.Origin())
+	m.view.SetDrawFunc(m.detectResize)
 
-	m.Events().Subscribe(events.HandleFunc(func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case gooster.EventOutput:
-			if _, err := output.Write(event.Data); err != nil {
-				m.Log().Error(errors.WithMessage(err, "write to output"))
-			}
-		}
-		return e
+	m.searchField = tview.NewInputField()
+	m.searchField.SetFieldBackgroundColor(m.cfg.Colors.SearchBg.Origin())
+	m.searchField.SetBackgroundColor(m.cfg.Colors.SearchBg.Origin())
+	m.searchField.SetChangedFunc(func(text string) {
+		m.search.Query = text
+		m.Events().Dispatch(EventSearch{Options: m.search})
+	})
+	m.searchField.SetDoneFunc(m.handleSearchDone)
+
+	// the search field is added to the layout, when the search is started
+	// (a flex item of zero size and proportion can't be drawn after the proportional ones)
+	m.layout = tview.NewFlex().SetDirection(tview.FlexRow)
+	m.layout.AddItem(m.view, 0, 1, true)
+
+	m.Events().Subscribe(
+		events.On(m.handleEventOutput),
+		events.On(func(gooster.EventExit) { m.handleEventExit() }),
+		events.On(func(gooster.EventTabClosed) { m.handleEventExit() }),
+		events.On(func(gooster.EventPaneClosed) { m.handleEventExit() }),
+		events.On(m.handleEventRestoreState),
+		events.Respond(m.handleQuerySessionState),
+		events.On(func(EventLoadOlder) { m.handleEventLoadOlder() }),
+		events.On(m.handleEventOpenBlock),
+		events.On(m.handleEventCloseBlock),
+		events.On(m.handleEventSelectBlock),
+		events.On(m.handleEventToggleBlock),
+		events.On(m.handleEventSaveBlock),
+		events.On(func(EventOpenSearch) { m.handleEventOpenSearch() }),
+		events.On(m.handleEventCloseSearch),
+		events.On(m.handleEventSearch),
+		events.On(m.handleEventSelectMatch),
+	)
+
+	gooster.HandleKeyEvents(m.view, gooster.KeyEventHandlers{
+		m.cfg.Keys.PrevBlock:           m.handleKeyPrevBlock,
+		m.cfg.Keys.NextBlock:           m.handleKeyNextBlock,
+		m.cfg.Keys.ToggleBlock:         m.handleKeyToggleBlock,
+		m.cfg.Keys.CopyBlock:           m.handleKeyCopyBlock,
+		m.cfg.Keys.RerunBlock:          m.handleKeyRerunBlock,
+		m.cfg.Keys.SaveBlock:           m.handleKeySaveBlock,
+		m.cfg.Keys.LoadOlder:           m.handleKeyLoadOlder,
+		m.cfg.Keys.Search:              m.handleKeySearch,
+		config.NewKey(tcell.KeyEscape): m.handleKeyEscape,
+	})
+
+	gooster.HandleKeyEvents(m.searchField, gooster.KeyEventHandlers{
+		m.cfg.Keys.SearchNext:  m.handleKeySearchNext,
+		m.cfg.Keys.SearchPrev:  m.handleKeySearchPrev,
+		m.cfg.Keys.SearchRegex: m.handleKeySearchRegex,
+		m.cfg.Keys.SearchCase:  m.handleKeySearchCase,
+		m.cfg.Keys.SearchBlock: m.handleKeySearchBlock,
+	})
+	m.updateSearchLabel()
+
+	return nil
+}
+
+// renderInterval is the minimal interval between the updates of the view by the output,
+// since the view parses the whole text again on every update.
+const renderInterval = 50 * time.Millisecond
+
+// renderOutput shows the new output right away, unless the view has been just updated.
+// Otherwise the update is queued, so that all output written in the meantime is shown at once.
+func (m *Module) renderOutput() {
+	m.mu.Lock()
+	if m.renderQueued {
+		m.mu.Unlock()
+		return
+	}
+	wait := renderInterval - time.Since(m.renderedAt)
+	m.renderQueued = wait > 0
+	m.mu.Unlock()
+
+	if wait <= 0 {
+		m.render()
+		return
+	}
+	time.AfterFunc(wait, func() {
+		m.Events().Dispatch(gooster.EventQueueUpdate{Update: func() {
+			m.mu.Lock()
+			m.renderQueued = false
+			m.mu.Unlock()
+			m.render()
+		}})
+	})
+}
+
+// render shows the updated output blocks.
+func (m *Module) render() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.renderedAt = time.Now()
+	m.view.SetText(m.blocks.render(renderConfig{
+		collapsedColor: fmt.Sprintf("#%06x", m.cfg.Colors.Collapsed.Origin().Hex()),
+		loadOlderKey:   keyName(m.cfg.Keys.LoadOlder),
+		matchColor:     fmt.Sprintf("#%06x", m.cfg.Colors.Match.Origin().Hex()),
+		matches:        m.matches,
 	}))
+}
 
+func (m *Module) initScrollback() error {
+	cfg := m.cfg.Scrollback
+	var spill *spillFile
+	if cfg.Spill && (cfg.MaxLines > 0 || cfg.MaxBytes > 0) {
+		var err error
+		if spill, err = newSpillFile(cfg.SpillDir); err != nil {
+			return err
+		}
+	}
+	m.blocks.setLimit(cfg.MaxLines, cfg.MaxBytes, spill)
 	return nil
 }
+
+// selectedBlock returns the currently selected command block or nil.
+func (m *Module) selectedBlock() *Block {
+	if m.selected == 0 {
+		return nil
+	}
+	return m.blocks.get(m.selected)
+}
+
+// detectResize notifies other modules about the new size of the output area,
+// so that running commands could adjust their terminal size.
+func (m *Module) detectResize(_ tcell.Screen, _, _, _, _ int) (int, int, int, int) {
+	x, y, width, height := m.view.GetInnerRect()
+	if width != m.width || height != m.height {
+		m.width, m.height = width, height
+		m.blocks.setHeight(height)
+		// the view is being drawn at the moment, so the event must not be handled synchronously
+		go m.Events().Dispatch(EventResize{Width: width, Height: height})
+	}
+	return x, y, width, height
+}
+
+func keyName(key config.Key) string {
+	if key.Type == tcell.KeyRune && key.Mod == tcell.ModNone {
+		return string(key.Rune)
+	}
+	return key.String()
+}
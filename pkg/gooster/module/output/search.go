@@ -0,0 +1,98 @@
+package output
+
+import (
+	"github.com/jumale/gooster/pkg/ansi"
+	"github.com/pkg/errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type SearchOptions struct {
+	Query         string
+	Regex         bool
+	CaseSensitive bool
+	// BlockID limits the search to a single block, if defined
+	BlockID int
+}
+
+// Match is an occurrence of the search query in the output.
+// Start and End are rune positions in the line of the block.
+type Match struct {
+	BlockID int
+	Line    int
+	Start   int
+	End     int
+}
+
+func (m Match) regionId(idx int) string {
+	return "match_" + strconv.Itoa(idx)
+}
+
+// search returns all matches of the query in the order they appear in the output.
+func (l *blockList) search(opts SearchOptions) ([]Match, error) {
+	if opts.Query == "" {
+		return nil, nil
+	}
+
+	pattern := opts.Query
+	if !opts.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !opts.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid search pattern")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var matches []Match
+	for _, block := range l.blocks {
+		if opts.BlockID != 0 && block.ID != opts.BlockID {
+			continue
+		}
+		for i, line := range strings.Split(block.Text(), "\n") {
+			for _, pos := range re.FindAllStringIndex(line, -1) {
+				if pos[0] == pos[1] {
+					continue
+				}
+				matches = append(matches, Match{
+					BlockID: block.ID,
+					Line:    i,
+					Start:   utf8.RuneCountInString(line[:pos[0]]),
+					End:     utf8.RuneCountInString(line[:pos[1]]),
+				})
+			}
+		}
+	}
+	return matches, nil
+}
+
+// blockMarks converts the matches of the block to marks, which highlight the matches
+// and wrap every one of them in its own region.
+func blockMarks(block *Block, matches []Match, color string) (marks []ansi.Mark) {
+	// a match region interrupts the block region, so it must be continued after the match
+	suffix := `[""]`
+	if block.IsCommand() {
+		suffix = `["` + block.regionId() + `"]`
+	}
+
+	for idx, match := range matches {
+		if match.BlockID != block.ID {
+			continue
+		}
+		marks = append(marks, ansi.Mark{
+			Line:   match.Line,
+			Start:  match.Start,
+			End:    match.End,
+			Prefix: `["` + match.regionId(idx) + `"][:` + color + `]`,
+			Suffix: suffix,
+		})
+	}
+	return marks
+}
@@ -0,0 +1,76 @@
+package output
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/ansi"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	assert := require.New(t)
+
+	l := newBlockList(ansi.WriterConfig{DefaultFg: tcell.ColorDefault, DefaultBg: tcell.ColorDefault})
+	_, err := l.write([]byte("Foo log\n"))
+	assert.NoError(err)
+	block := l.open("ls")
+	_, err = l.write([]byte("foo.txt\n\033[31mbär\033[0m foo.go\n"))
+	assert.NoError(err)
+	l.close(0)
+
+	t.Run("should find plain text ignoring case", func(t *testing.T) {
+		matches, err := l.search(SearchOptions{Query: "foo"})
+		assert.NoError(err)
+		assert.Equal([]Match{
+			{BlockID: 1, Line: 0, Start: 0, End: 3},
+			{BlockID: block.ID, Line: 0, Start: 0, End: 3},
+			{BlockID: block.ID, Line: 1, Start: 4, End: 7},
+		}, matches)
+	})
+
+	t.Run("should find case sensitive text", func(t *testing.T) {
+		matches, err := l.search(SearchOptions{Query: "Foo", CaseSensitive: true})
+		assert.NoError(err)
+		assert.Equal([]Match{{BlockID: 1, Line: 0, Start: 0, End: 3}}, matches)
+	})
+
+	t.Run("should not treat plain query as regex", func(t *testing.T) {
+		matches, err := l.search(SearchOptions{Query: "foo.go"})
+		assert.NoError(err)
+		assert.Len(matches, 1)
+
+		matches, err = l.search(SearchOptions{Query: "foo.g"})
+		assert.NoError(err)
+		assert.Len(matches, 1)
+	})
+
+	t.Run("should find regex", func(t *testing.T) {
+		matches, err := l.search(SearchOptions{Query: `\.(txt|go)$`, Regex: true})
+		assert.NoError(err)
+		assert.Equal([]Match{
+			{BlockID: block.ID, Line: 0, Start: 3, End: 7},
+			{BlockID: block.ID, Line: 1, Start: 7, End: 10},
+		}, matches)
+
+		_, err = l.search(SearchOptions{Query: `(foo`, Regex: true})
+		assert.Error(err)
+	})
+
+	t.Run("should limit search to the block", func(t *testing.T) {
+		matches, err := l.search(SearchOptions{Query: "foo", BlockID: block.ID})
+		assert.NoError(err)
+		assert.Len(matches, 2)
+	})
+
+	t.Run("should highlight matches", func(t *testing.T) {
+		matches, err := l.search(SearchOptions{Query: "bär"})
+		assert.NoError(err)
+		assert.Equal(
+			`Foo log`+"\n"+
+				`["block_2"][-:-:-]foo.txt`+"\n"+
+				`[red:-:-]["match_0"][:olive]bär["block_2"][-:-:-] foo.go`+"\n"+
+				`[-:-:-][""]`,
+			l.render(renderConfig{matchColor: "olive", matches: matches}),
+		)
+	})
+}
@@ -0,0 +1,94 @@
+package output
+
+import (
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// spillFile stores the lines evicted from the scrollback in a temporary file,
+// so that they could be paged back in on demand.
+type spillFile struct {
+	file *os.File
+	// start offset of every stored line
+	offsets []int64
+	size    int64
+	// index of the oldest line, which has been paged back in (number of the lines, if none has been)
+	start int
+}
+
+func newSpillFile(dir string) (*spillFile, error) {
+	file, err := ioutil.TempFile(dir, "gooster-output-*.log")
+	if err != nil {
+		return nil, errors.WithMessage(err, "create output spill file")
+	}
+	return &spillFile{file: file}, nil
+}
+
+// append stores the rendered lines at the end of the file.
+func (s *spillFile) append(lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	// the new lines are not paged in, but they are newer than the paged in ones
+	rewound := s.start == len(s.offsets)
+
+	var buf strings.Builder
+	for _, line := range lines {
+		s.offsets = append(s.offsets, s.size+int64(buf.Len()))
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if rewound {
+		s.start = len(s.offsets)
+	}
+	n, err := s.file.WriteAt([]byte(buf.String()), s.size)
+	s.size += int64(n)
+	if err != nil {
+		return errors.WithMessage(err, "write output spill file")
+	}
+	return nil
+}
+
+// remaining returns number of the lines, which have not been paged back in yet.
+func (s *spillFile) remaining() int {
+	return s.start
+}
+
+// page reads up to n latest lines, which have not been paged back in yet.
+func (s *spillFile) page(n int) (string, error) {
+	end := s.start
+	start := end - n
+	if start < 0 {
+		start = 0
+	}
+	if start == end {
+		return "", nil
+	}
+
+	endOffset := s.size
+	if end < len(s.offsets) {
+		endOffset = s.offsets[end]
+	}
+	data := make([]byte, endOffset-s.offsets[start])
+	if _, err := s.file.ReadAt(data, s.offsets[start]); err != nil && err != io.EOF {
+		return "", errors.WithMessage(err, "read output spill file")
+	}
+	s.start = start
+	return string(data), nil
+}
+
+// rewind forgets the paged in lines, so that they could be paged in again.
+func (s *spillFile) rewind() {
+	s.start = len(s.offsets)
+}
+
+// close removes the file.
+func (s *spillFile) close() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(s.file.Name())
+}
@@ -0,0 +1,199 @@
+package prompt
+
+import (
+	"github.com/jumale/gooster/pkg/command"
+	"github.com/jumale/gooster/pkg/gooster/module/output"
+	"github.com/pkg/errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// execBuiltin handles the job control and history commands, which are executed by gooster itself
+// instead of the shell. It returns false if the input is not a builtin command.
+func (m *Module) execBuiltin(input string) bool {
+	args := simpleCommand(input)
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "jobs":
+		m.printJobs()
+
+	case "fg":
+		if job := m.findJob(args, m.lastJob()); job != nil {
+			m.Events().Dispatch(EventSelectJob{ID: job.ID})
+		}
+
+	case "bg":
+		if job := m.findJob(args, m.jobs.Foreground()); job != nil {
+			m.moveToBackground(job)
+		}
+
+	case "kill":
+		// "kill" without a job spec is a regular shell command
+		if len(args) < 2 || !strings.HasPrefix(args[1], "%") {
+			return false
+		}
+		if job := m.findJob(args, nil); job != nil {
+			m.check(job.command.Kill(), "kill job")
+		}
+
+	case "history":
+		// other "history" commands are handled by the shell
+		if len(args) != 3 || (args[1] != "import" && args[1] != "export") {
+			return false
+		}
+		m.history.Add(input)
+		if args[1] == "import" {
+			m.importHistory(args[2])
+		} else {
+			m.exportHistory(args[2])
+		}
+
+	default:
+		return false
+	}
+
+	m.clearPrompt()
+	return true
+}
+
+// importHistory adds the commands from the file in the bash history format.
+func (m *Module) importHistory(path string) {
+	f, err := m.Fs().Open(m.absPath(path))
+	if err != nil {
+		m.Log().Error(errors.WithMessage(err, "open imported history"))
+		return
+	}
+	defer func() { m.check(f.Close(), "close imported history") }()
+
+	count, err := m.history.Import(f)
+	if err != nil {
+		m.Log().Error(err)
+		return
+	}
+	m.Output().WriteF("Imported %d commands from %s\n", count, path)
+}
+
+// exportHistory writes the commands to the file in the bash history format.
+func (m *Module) exportHistory(path string) {
+	f, err := m.Fs().Create(m.absPath(path))
+	if err != nil {
+		m.Log().Error(errors.WithMessage(err, "create exported history"))
+		return
+	}
+	defer func() { m.check(f.Close(), "close exported history") }()
+
+	if err := m.history.Export(f); err != nil {
+		m.Log().Error(err)
+		return
+	}
+	m.Output().WriteF("Exported history to %s\n", path)
+}
+
+// absPath resolves the path against the work dir of the shell.
+func (m *Module) absPath(path string) string {
+	if strings.HasPrefix(path, "~") {
+		if home, err := m.Fs().UserHomeDir(); err == nil {
+			path = strings.Replace(path, "~", home, 1)
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	_, workDir := m.state()
+	return filepath.Join(workDir, path)
+}
+
+func (m *Module) printJobs() {
+	fg := m.jobs.Foreground()
+	for _, job := range m.jobs.List() {
+		mark := " "
+		if job == fg {
+			mark = "+"
+		}
+		m.Output().WriteF("[%d]%s %-8s %s\n", job.ID, mark, time.Since(job.StartedAt).Round(time.Second), job.Cmd)
+	}
+}
+
+func (m *Module) moveToBackground(job *Job) {
+	if m.jobs.Foreground() != job {
+		m.Log().InfoF("Job [%d] is already running in background", job.ID)
+		return
+	}
+
+	m.jobs.SetForeground(nil, nil)
+	// background jobs must not take the terminal
+	job.command.screen.setOnSwitch(nil)
+	m.Events().Dispatch(output.EventCloseBlock{Background: true})
+	// the job keeps its shell session, so the next commands need a new one
+	if shell, _ := m.state(); job.shell == shell {
+		m.setShell(m.newShell())
+	}
+
+	lineBreak := ""
+	if job.command.LastChar() != newLine {
+		lineBreak = "\n"
+	}
+	m.Output().WriteF("%s[%d] %s &\n", lineBreak, job.ID, job.Cmd)
+}
+
+// simpleCommand returns the words of the input, if it's a single command without pipes, lists and redirections.
+// Otherwise the input is executed by the shell, even if it starts with a builtin (e.g. "jobs | grep foo").
+func simpleCommand(input string) []string {
+	commands, err := command.ParseCommands(input)
+	if err != nil || len(commands) != 1 || commands[0].Command == "" {
+		return nil
+	}
+	args := append([]string{commands[0].Command}, commands[0].Args...)
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "&<>`\n") || strings.Contains(arg, "$(") {
+			return nil
+		}
+	}
+	return args
+}
+
+// findJob returns the job defined by the job spec ("N" or "%N") in the command arguments,
+// or the fallback job if the spec is not provided.
+func (m *Module) findJob(args []string, fallback *Job) *Job {
+	if len(args) < 2 {
+		if fallback == nil {
+			m.Log().ErrorF("%s: no current job", args[0])
+		}
+		return fallback
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(args[1], "%"))
+	if err != nil {
+		m.Log().ErrorF("%s: invalid job spec '%s'", args[0], args[1])
+		return nil
+	}
+
+	job := m.jobs.Get(id)
+	if job == nil {
+		m.Log().ErrorF("%s: job [%d] not found", args[0], id)
+	}
+	return job
+}
+
+func (m *Module) lastJob() *Job {
+	list := m.jobs.List()
+	if len(list) == 0 {
+		return nil
+	}
+	return list[len(list)-1]
+}
+
+// detectBackground checks if the command should be executed in background (ends with "&"),
+// and returns the command without the trailing "&".
+func detectBackground(cmd string) (string, bool) {
+	trimmed := strings.TrimSpace(cmd)
+	if !strings.HasSuffix(trimmed, "&") || strings.HasSuffix(trimmed, "&&") {
+		return cmd, false
+	}
+	return strings.TrimSpace(strings.TrimSuffix(trimmed, "&")), true
+}
@@ -1,25 +1,20 @@
 package prompt
 
 import (
-	"context"
 	"io"
-	"os/exec"
+	"io/ioutil"
 )
 
+// Command is a single command executed in a shell session.
 type Command struct {
 	cmd      string
-	runner   *exec.Cmd
-	cancel   context.CancelFunc
-	input    io.WriteCloser
+	shell    *Shell
+	screen   *screenSwitch
 	lastChar byte
 }
 
-func NewCommand(cmd string) *Command {
-	ctx, cancel := context.WithCancel(context.Background())
-	c := exec.CommandContext(ctx, "bash", "-l", "-c", cmd)
-	input, _ := c.StdinPipe()
-
-	return &Command{cmd: cmd, runner: c, cancel: cancel, input: input}
+func NewCommand(cmd string, shell *Shell) *Command {
+	return &Command{cmd: cmd, shell: shell, screen: newScreenSwitch(ioutil.Discard)}
 }
 
 func (c *Command) Command() string {
@@ -27,28 +22,30 @@ func (c *Command) Command() string {
 }
 
 func (c *Command) SetOutput(w io.Writer) *Command {
-	w = &writerHook{
-		target: w,
+	c.screen.app = &writerHook{
+		target: &crlfWriter{target: w},
 		hook: func(p []byte) {
 			c.lastChar = p[len(p)-1]
 		},
 	}
-	c.runner.Stdout = w
-	c.runner.Stderr = w
 	return c
 }
 
-func (c *Command) Run() error {
-	return c.runner.Run()
+func (c *Command) Run() (Result, error) {
+	return c.shell.Exec(c.cmd, c.screen)
+}
+
+func (c *Command) Cancel() error {
+	return c.shell.Interrupt()
 }
 
-func (c *Command) Cancel() {
-	_ = c.input.Close()
-	c.cancel()
+// Kill terminates the command, even if it ignores interrupts.
+func (c *Command) Kill() error {
+	return c.shell.Kill()
 }
 
 func (c *Command) Write(p []byte) (n int, err error) {
-	return c.input.Write(p)
+	return c.shell.Write(p)
 }
 
 func (c *Command) LastChar() byte {
@@ -64,3 +61,43 @@ func (l *writerHook) Write(p []byte) (n int, err error) {
 	l.hook(p)
 	return l.target.Write(p)
 }
+
+const carriageReturn byte = 13
+
+// crlfWriter converts "\r\n" line endings, produced by the terminal, to "\n".
+type crlfWriter struct {
+	target  io.Writer
+	pending bool
+}
+
+func (w *crlfWriter) Write(p []byte) (n int, err error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	buf := make([]byte, 0, len(p)+1)
+	if w.pending {
+		w.pending = false
+		if p[0] != newLine {
+			buf = append(buf, carriageReturn)
+		}
+	}
+
+	for i, b := range p {
+		if b != carriageReturn {
+			buf = append(buf, b)
+			continue
+		}
+		if i == len(p)-1 {
+			// the line ending can be split between two writes
+			w.pending = true
+		} else if p[i+1] != newLine {
+			buf = append(buf, b)
+		}
+	}
+
+	if _, err = w.target.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -1,6 +1,7 @@
 package prompt
 
 import (
+	"bytes"
 	"github.com/jumale/gooster/pkg/filesys/fstub"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -19,40 +20,79 @@ func TestDetectWorkDirPath(t *testing.T) {
 	fs.Root().Add("/work/dir/some/file", fstub.NewFile())
 
 	t.Run("should detect absolute path from cd command", func(t *testing.T) {
-		assert.Equal("/foo/bar/baz", detectWorkDirPath(fs, "cd /foo/bar/baz"))
+		assert.Equal("/foo/bar/baz", detectWorkDirPath(fs, "/work/dir", "cd /foo/bar/baz"))
 	})
 
 	t.Run("should detect relative path from cd command", func(t *testing.T) {
-		assert.Equal("/work/dir/foo/bar", detectWorkDirPath(fs, "cd foo/bar"))
+		assert.Equal("/work/dir/foo/bar", detectWorkDirPath(fs, "/work/dir", "cd foo/bar"))
 	})
 
 	t.Run("should detect home path from cd command", func(t *testing.T) {
-		assert.Equal("/home/dir/foo/bar", detectWorkDirPath(fs, "cd ~/foo/bar"))
+		assert.Equal("/home/dir/foo/bar", detectWorkDirPath(fs, "/work/dir", "cd ~/foo/bar"))
 	})
 
 	t.Run("should detect home dir from cd command", func(t *testing.T) {
-		assert.Equal("/home/dir", detectWorkDirPath(fs, "cd ~"))
+		assert.Equal("/home/dir", detectWorkDirPath(fs, "/work/dir", "cd ~"))
 	})
 
 	t.Run("should detect raw absolute path", func(t *testing.T) {
-		assert.Equal("/foo/bar/baz", detectWorkDirPath(fs, "/foo/bar/baz"))
+		assert.Equal("/foo/bar/baz", detectWorkDirPath(fs, "/work/dir", "/foo/bar/baz"))
 	})
 
 	t.Run("should detect raw relative path", func(t *testing.T) {
-		assert.Equal("/work/dir/foo/bar", detectWorkDirPath(fs, "./foo/bar"))
+		assert.Equal("/work/dir/foo/bar", detectWorkDirPath(fs, "/work/dir", "./foo/bar"))
+	})
+
+	t.Run("should resolve parent dirs against the work dir", func(t *testing.T) {
+		assert.Equal("/work/dir/foo", detectWorkDirPath(fs, "/work/dir/foo/bar", "cd .."))
 	})
 
 	t.Run("should return empty for some random commands", func(t *testing.T) {
-		assert.Empty(detectWorkDirPath(fs, "ls"))
-		assert.Empty(detectWorkDirPath(fs, "cp ./foo/bar"))
-		assert.Empty(detectWorkDirPath(fs, "rm /foo/bar"))
+		assert.Empty(detectWorkDirPath(fs, "/work/dir", "ls"))
+		assert.Empty(detectWorkDirPath(fs, "/work/dir", "cp ./foo/bar"))
+		assert.Empty(detectWorkDirPath(fs, "/work/dir", "rm /foo/bar"))
 	})
 
 	t.Run("should return empty if it looks like a path, but does not start with '/', './', or '../'", func(t *testing.T) {
-		assert.Empty(detectWorkDirPath(fs, "foo/bar/baz"))
+		assert.Empty(detectWorkDirPath(fs, "/work/dir", "foo/bar/baz"))
 	})
 
 	t.Run("should return empty if the target is not a directory", func(t *testing.T) {
-		assert.Empty(detectWorkDirPath(fs, "./some/file"))
+		assert.Empty(detectWorkDirPath(fs, "/work/dir", "./some/file"))
+	})
+}
+
+func TestCrlfWriter(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should convert terminal line endings", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		w := &crlfWriter{target: buf}
+
+		_, err := w.Write([]byte("foo\r\nbar\r\n"))
+		assert.NoError(err)
+		assert.Equal("foo\nbar\n", buf.String())
+	})
+
+	t.Run("should keep single carriage returns", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		w := &crlfWriter{target: buf}
+
+		_, err := w.Write([]byte("foo\rbar\r"))
+		assert.NoError(err)
+		_, err = w.Write([]byte("baz"))
+		assert.NoError(err)
+		assert.Equal("foo\rbar\rbaz", buf.String())
+	})
+
+	t.Run("should convert line endings split between writes", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		w := &crlfWriter{target: buf}
+
+		_, err := w.Write([]byte("foo\r"))
+		assert.NoError(err)
+		_, err = w.Write([]byte("\nbar"))
+		assert.NoError(err)
+		assert.Equal("foo\nbar", buf.String())
 	})
 }
@@ -10,8 +10,21 @@ type Config struct {
 	PrintDivider bool         `json:"print_divider"`
 	PrintCommand bool         `json:"print_command"`
 	HistoryFile  string       `json:"history_file"`
-	FieldWidth   int          `json:"field_width"`
-	Keys         KeysConfig   `json:"keys"`
+	// ExtendedHistoryFile keeps the history with the directory, exit code and duration of the commands
+	ExtendedHistoryFile string `json:"extended_history_file"`
+	// HistorySize is the max number of the commands in the history files (0 is unlimited)
+	HistorySize int `json:"history_size"`
+	// HistoryIgnoreSpace skips the commands starting with a space
+	HistoryIgnoreSpace bool `json:"history_ignore_space"`
+	// HistoryIgnore are the patterns of the commands, which are not written to the history (e.g. with secrets)
+	HistoryIgnore []string `json:"history_ignore"`
+	Shell         string   `json:"shell"`
+	ShellArgs     []string `json:"shell_args"`
+	FullScreen    []string `json:"full_screen"` // commands, which get the whole terminal
+	FieldWidth    int      `json:"field_width"`
+	// MaxHeight is the max number of lines, which the prompt grows to with a multi-line input (0 is unlimited)
+	MaxHeight int        `json:"max_height"`
+	Keys      KeysConfig `json:"keys"`
 }
 
 type ColorsConfig struct {
@@ -20,9 +33,46 @@ type ColorsConfig struct {
 	Text    config.Color `json:"text"`
 	Divider config.Color `json:"divider"`
 	Command config.Color `json:"command"`
+	// Match highlights the matched characters of the history search results
+	Match config.Color `json:"match"`
+	// Selected is the background of the selected history search result
+	Selected config.Color `json:"selected"`
 }
 
 type KeysConfig struct {
 	HistoryNext config.Key `json:"history_next"`
 	HistoryPrev config.Key `json:"history_prev"`
+	// HistoryFilter defines the entries, which are walked by HistoryPrev and HistoryNext
+	HistoryFilter HistoryFilter `json:"history_filter"`
+	// HistoryBindings are additional keys for the history navigation, each pair with its own filter
+	HistoryBindings []HistoryBinding `json:"history_bindings"`
+	Background      config.Key       `json:"background"`
+	// HistorySearch opens the history search, it also moves to the next result while the search is open.
+	HistorySearch config.Key `json:"history_search"`
+	// HistoryEdit puts the selected history search result to the prompt instead of running it.
+	HistoryEdit config.Key `json:"history_edit"`
+	// HistoryInDir toggles the history search of the commands, which have been run in the current directory.
+	HistoryInDir config.Key `json:"history_in_dir"`
+	// HistorySucceeded toggles the history search of the commands, which have finished successfully.
+	HistorySucceeded config.Key `json:"history_succeeded"`
+	// NewLine continues the input on the next line instead of running it.
+	NewLine []config.Key `json:"new_line"`
 }
+
+type HistoryBinding struct {
+	Prev   config.Key    `json:"prev"`
+	Next   config.Key    `json:"next"`
+	Filter HistoryFilter `json:"filter"`
+}
+
+// HistoryFilter defines the history entries, which are walked, when the prompt has text.
+type HistoryFilter string
+
+const (
+	// HistoryAll walks all entries, whatever is typed
+	HistoryAll HistoryFilter = "all"
+	// HistoryPrefix walks the entries, which start with the typed text
+	HistoryPrefix HistoryFilter = "prefix"
+	// HistorySubstring walks the entries, which contain the typed text
+	HistorySubstring HistoryFilter = "substring"
+)
@@ -0,0 +1,107 @@
+package prompt
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"strings"
+)
+
+// editor is the input field of the prompt, which supports multi-line input.
+// The last line is edited in the field, the previous lines are shown above it.
+type editor struct {
+	*tview.InputField
+	label string
+	// lines are the finished lines of the multi-line input
+	lines  []string
+	colors ColorsConfig
+}
+
+func newEditor(label string, colors ColorsConfig) *editor {
+	e := &editor{InputField: tview.NewInputField(), label: label, colors: colors}
+	e.SetLabel(label)
+	return e
+}
+
+// Input returns the whole input including the previous lines.
+func (e *editor) Input() string {
+	return strings.Join(append(append([]string(nil), e.lines...), e.GetText()), "\n")
+}
+
+// SetInput puts the text to the editor, the last line of the text is edited in the field.
+func (e *editor) SetInput(text string) {
+	lines := strings.Split(text, "\n")
+	e.lines = lines[:len(lines)-1]
+	e.SetText(lines[len(lines)-1])
+	e.updateLabel()
+}
+
+// NewLine finishes the edited line and starts a new one.
+func (e *editor) NewLine() {
+	e.lines = append(e.lines, e.GetText())
+	e.SetText("")
+	e.updateLabel()
+}
+
+// JoinLine puts the previous line back to the field, it returns false if there is no previous line.
+func (e *editor) JoinLine() bool {
+	if len(e.lines) == 0 {
+		return false
+	}
+	last := len(e.lines) - 1
+	e.SetText(e.lines[last] + e.GetText())
+	e.lines = e.lines[:last]
+	e.updateLabel()
+	return true
+}
+
+// Height returns the number of the lines of the input.
+func (e *editor) Height() int {
+	return len(e.lines) + 1
+}
+
+// updateLabel shows the label on the first line only, the next lines are indented.
+func (e *editor) updateLabel() {
+	if len(e.lines) == 0 {
+		e.SetLabel(e.label)
+	} else {
+		e.SetLabel(strings.Repeat(" ", tview.TaggedStringWidth(e.label)))
+	}
+}
+
+func (e *editor) Draw(screen tcell.Screen) {
+	x, y, width, height := e.GetRect()
+	// if there is not enough space, then the latest lines are shown
+	visible := e.lines
+	if hidden := len(e.lines) + 1 - height; hidden > 0 {
+		visible = e.lines[minInt(hidden, len(e.lines)):]
+	}
+
+	style := tcell.StyleDefault.Background(e.colors.Bg.Origin())
+	for i, line := range visible {
+		for col := x; col < x+width; col++ {
+			screen.SetContent(col, y+i, ' ', nil, style)
+		}
+		label := strings.Repeat(" ", tview.TaggedStringWidth(e.label))
+		if i == 0 && len(visible) == len(e.lines) {
+			label = e.label
+		}
+		_, labelWidth := tview.Print(screen, tview.Escape(label), x, y+i, width, tview.AlignLeft, e.colors.Label.Origin())
+		tview.Print(screen, tview.Escape(line), x+labelWidth, y+i, width-labelWidth, tview.AlignLeft, e.colors.Text.Origin())
+	}
+
+	if len(visible) == 0 {
+		e.InputField.Draw(screen)
+		return
+	}
+	// the field is drawn below the previous lines
+	e.InputField.SetRect(x, y+len(visible), width, height-len(visible))
+	e.InputField.Draw(screen)
+	e.InputField.SetRect(x, y, width, height)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,82 @@
+package prompt
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should keep the previous lines of the input", func(t *testing.T) {
+		e := newEditor(" > ", ColorsConfig{})
+		e.SetText("for i in 1 2")
+		e.NewLine()
+		e.SetText("do echo $i; done")
+
+		assert.Equal("for i in 1 2\ndo echo $i; done", e.Input())
+		assert.Equal(2, e.Height())
+		assert.Equal("   ", e.GetLabel())
+	})
+
+	t.Run("should join the line with the previous one", func(t *testing.T) {
+		e := newEditor(" > ", ColorsConfig{})
+		e.SetInput("foo\nbar")
+		assert.Equal("bar", e.GetText())
+
+		assert.True(e.JoinLine())
+		assert.Equal("foobar", e.Input())
+		assert.Equal(" > ", e.GetLabel())
+		assert.False(e.JoinLine())
+	})
+
+	t.Run("should detect incomplete input", func(t *testing.T) {
+		incomplete := []string{
+			`echo "foo`,
+			"echo 'foo\nbar",
+			`ls \`,
+			"for i in 1 2",
+			"for i in 1 2\ndo\n  echo $i",
+			"if true; then",
+			"case $x in\na) echo a;;",
+			"greet() {",
+			"greet () {",
+			"function greet {",
+			"function greet() {",
+			"cat <<EOF\nfoo",
+		}
+		for _, input := range incomplete {
+			assert.True(incompleteInput(input), input)
+		}
+
+		complete := []string{
+			`echo "foo"`,
+			"echo 'foo\nbar'",
+			"for i in 1 2; do echo $i; done",
+			"for i in 1 2\ndo\n  echo $i\ndone",
+			"if true; then echo; fi",
+			"echo if",
+			"greet() {\n  echo hi\n}",
+			"cat <<-EOF\nfoo\n\tEOF",
+			"cat <<< foo",
+			"echo $((1<<4))",
+			"(( x = 1 << 2 ))",
+			`echo "a << b"`,
+			"echo 'a <<b'",
+			"cat <<EOF\ndon't\nEOF",
+			"echo {",
+			`find . -exec echo {} \; -exec echo {`,
+		}
+		for _, input := range complete {
+			assert.False(incompleteInput(input), input)
+		}
+	})
+
+	t.Run("should join lines of multi-line command for history", func(t *testing.T) {
+		assert.Equal("for i in 1 2; do echo $i; done", historyLine("for i in 1 2\ndo\n  echo $i\ndone"))
+		assert.Equal("ls -la | grep foo", historyLine("ls -la \\\n| grep foo"))
+		assert.Equal("cat <<EOF > foo\n  bar\nEOF\nls", historyLine("cat <<EOF > foo\n  bar\nEOF\nls"))
+		assert.Equal("echo 'foo\n  bar'; ls", historyLine("echo 'foo\n  bar'\nls"))
+		assert.Equal("echo foo", historyLine("echo foo"))
+	})
+}
@@ -1,5 +1,10 @@
 package prompt
 
+import (
+	"syscall"
+	"time"
+)
+
 type EventSetPrompt struct {
 	Input string
 	Focus bool
@@ -15,6 +20,16 @@ func (e EventClearPrompt) NeedsDraw() bool {
 	return true
 }
 
+// EventSearchHistory opens the history search with the initial query.
+// The selected command is either executed, or put to the prompt for editing.
+type EventSearchHistory struct {
+	Query string
+	// InDir shows only the commands, which have been run in the current directory
+	InDir bool
+	// Succeeded shows only the commands, which have finished successfully
+	Succeeded bool
+}
+
 type EventExecCommand struct {
 	Cmd string
 }
@@ -22,3 +37,41 @@ type EventExecCommand struct {
 type EventSendUserInput struct {
 	Input string
 }
+
+type EventJobStarted struct {
+	ID         int
+	Cmd        string
+	Background bool
+}
+
+type EventJobFinished struct {
+	ID       int
+	Cmd      string
+	ExitCode int
+	Duration time.Duration
+}
+
+// EventSelectJob brings the job to foreground:
+// its output is streamed to the app output and it receives the user input.
+type EventSelectJob struct {
+	ID int
+}
+
+// EventCommandFinished is dispatched when a command (running in foreground or background) has finished.
+type EventCommandFinished struct {
+	Cmd         string
+	ExitCode    int
+	Signal      syscall.Signal // signal, which terminated the shell of the command, or 0 (see Result.Signal)
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	WorkDir     string // work dir of the shell after the command has finished
+	OutputBytes int
+}
+
+func (e EventCommandFinished) Success() bool {
+	return e.ExitCode == 0
+}
+
+func (e EventCommandFinished) Duration() time.Duration {
+	return e.FinishedAt.Sub(e.StartedAt)
+}
@@ -0,0 +1,248 @@
+package prompt
+
+import (
+	"bytes"
+	"github.com/creack/pty"
+	"github.com/jumale/gooster/pkg/gooster"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// altScreenSequences switch the terminal to the alternate screen buffer.
+// Printing any of them is a clear sign of a full-screen application.
+var altScreenSequences = [][]byte{
+	[]byte("\033[?1049h"),
+	[]byte("\033[?1047h"),
+	[]byte("\033[?47h"),
+}
+
+// isFullScreen checks if the command is listed in the full-screen commands config.
+func (m *Module) isFullScreen(cmd string) bool {
+	cmd = strings.TrimSpace(cmd)
+	for _, fullScreen := range m.cfg.FullScreen {
+		if cmd == fullScreen || strings.HasPrefix(cmd, fullScreen+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// suspendFor suspends the app and hands the real terminal over to the job.
+func (m *Module) suspendFor(job *Job) {
+	m.Log().DebugF("Handing terminal over to `%s`", job.Cmd)
+	m.Events().Dispatch(gooster.EventSuspend{Run: func() {
+		m.handOver(job)
+	}})
+}
+
+// handOver gives the real terminal to the job, and blocks until the job is finished.
+func (m *Module) handOver(job *Job) {
+	screen := job.command.screen
+	defer screen.release()
+
+	select {
+	case <-job.done:
+		return
+	default:
+	}
+
+	restore, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		m.Log().Error(errors.WithMessage(err, "switch terminal to raw mode"))
+		return
+	}
+	defer restore()
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+	inheritSize(job.shell)
+	defer func() {
+		if m.termWidth > 0 && m.termHeight > 0 {
+			job.shell.SetSize(m.termWidth, m.termHeight)
+		}
+	}()
+
+	screen.attach(os.Stdout)
+	if err := forwardInput(int(os.Stdin.Fd()), job, winch); err != nil {
+		m.Log().Error(errors.WithMessage(err, "forward terminal input"))
+	}
+}
+
+// inputPollTimeout is in milliseconds.
+const inputPollTimeout = 100
+
+// forwardInput sends the terminal input to the job until the job is finished.
+// The input is polled with a timeout instead of a blocking read,
+// so that no keystrokes are stolen from the app when the job is done.
+func forwardInput(fd int, job *Job, winch <-chan os.Signal) error {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	buf := make([]byte, 1024)
+	for {
+		select {
+		case <-job.done:
+			return nil
+		case <-winch:
+			inheritSize(job.shell)
+		default:
+		}
+
+		n, err := unix.Poll(fds, inputPollTimeout)
+		if err == unix.EINTR {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if n == 0 || fds[0].Revents&unix.POLLIN == 0 {
+			continue
+		}
+
+		n, err = unix.Read(fd, buf)
+		if err != nil {
+			return err
+		}
+		if _, err = job.command.Write(buf[:n]); err != nil {
+			return err
+		}
+	}
+}
+
+// inheritSize applies the size of the real terminal to the shell.
+func inheritSize(shell *Shell) {
+	if size, err := pty.GetsizeFull(os.Stdout); err == nil {
+		shell.SetSize(int(size.Cols), int(size.Rows))
+	}
+}
+
+// makeRaw puts the terminal into raw mode, so that every keystroke
+// (including Ctrl-C, Ctrl-Z, etc) is passed to the child as is.
+// It returns a function, which restores the previous terminal state.
+func makeRaw(fd int) (restore func(), err error) {
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+	prev := *termios
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB
+	termios.Cflag |= unix.CS8
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+	if err = unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = unix.IoctlSetTermios(fd, ioctlSetTermios, &prev)
+	}, nil
+}
+
+// screenSwitch routes the command output either to the app,
+// or - while the command owns the real terminal - directly to the terminal.
+// The output, printed while the app is being suspended, is held back
+// and flushed as soon as the terminal is handed over.
+type screenSwitch struct {
+	mu       *sync.Mutex
+	app      io.Writer
+	terminal io.Writer
+	pending  []byte
+	holding  bool
+	onSwitch func()
+}
+
+func newScreenSwitch(app io.Writer) *screenSwitch {
+	return &screenSwitch{mu: &sync.Mutex{}, app: app}
+}
+
+func (s *screenSwitch) Write(p []byte) (n int, err error) {
+	s.mu.Lock()
+	switch {
+	case s.terminal != nil:
+		defer s.mu.Unlock()
+		return s.terminal.Write(p)
+	case s.holding:
+		defer s.mu.Unlock()
+		s.pending = append(s.pending, p...)
+		return len(p), nil
+	}
+
+	pos := indexAltScreen(p)
+	if pos < 0 || s.onSwitch == nil {
+		defer s.mu.Unlock()
+		return s.app.Write(p)
+	}
+
+	s.holding = true
+	s.pending = append(s.pending, p[pos:]...)
+	onSwitch := s.onSwitch
+	s.mu.Unlock()
+
+	if pos > 0 {
+		if _, err = s.app.Write(p[:pos]); err != nil {
+			return 0, err
+		}
+	}
+	onSwitch()
+	return len(p), nil
+}
+
+// setOnSwitch defines the function, which is called when the command switches to the alternate screen.
+func (s *screenSwitch) setOnSwitch(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onSwitch = fn
+}
+
+// hold starts holding the output back until the terminal is attached.
+func (s *screenSwitch) hold() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.holding = true
+}
+
+// attach streams the held and all further output to the terminal.
+func (s *screenSwitch) attach(terminal io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) > 0 {
+		_, _ = terminal.Write(s.pending)
+		s.pending = nil
+	}
+	s.terminal = terminal
+}
+
+// release returns the output back to the app.
+// If the terminal has never been attached, the held output is passed to the app.
+func (s *screenSwitch) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) > 0 {
+		_, _ = s.app.Write(s.pending)
+		s.pending = nil
+	}
+	s.terminal = nil
+	s.holding = false
+}
+
+func indexAltScreen(p []byte) int {
+	pos := -1
+	for _, seq := range altScreenSequences {
+		if i := bytes.Index(p, seq); i >= 0 && (pos < 0 || i < pos) {
+			pos = i
+		}
+	}
+	return pos
+}
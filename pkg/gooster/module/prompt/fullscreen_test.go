@@ -0,0 +1,59 @@
+package prompt
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestScreenSwitch(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should switch to terminal when alternate screen is requested", func(t *testing.T) {
+		app, terminal := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+		switched := false
+		s := newScreenSwitch(app)
+		s.onSwitch = func() { switched = true }
+
+		_, _ = s.Write([]byte("foo"))
+		_, _ = s.Write([]byte("bar\033[?1049hbaz"))
+		assert.True(switched)
+		assert.Equal("foobar", app.String())
+
+		t.Run("and flush the held output when the terminal is attached", func(t *testing.T) {
+			s.attach(terminal)
+			_, _ = s.Write([]byte("qux"))
+			assert.Equal("\033[?1049hbazqux", terminal.String())
+			assert.Equal("foobar", app.String())
+		})
+
+		t.Run("and return back to the app when released", func(t *testing.T) {
+			s.release()
+			_, _ = s.Write([]byte("done"))
+			assert.Equal("foobardone", app.String())
+		})
+	})
+
+	t.Run("should pass the held output to the app, if the terminal was never attached", func(t *testing.T) {
+		app := bytes.NewBuffer(nil)
+		s := newScreenSwitch(app)
+		s.hold()
+
+		_, _ = s.Write([]byte("foo"))
+		assert.Empty(app.String())
+
+		s.release()
+		assert.Equal("foo", app.String())
+	})
+}
+
+func TestIsFullScreen(t *testing.T) {
+	assert := require.New(t)
+	m := NewModule()
+
+	assert.True(m.isFullScreen("vim"))
+	assert.True(m.isFullScreen("vim main.go"))
+	assert.True(m.isFullScreen("git add -p"))
+	assert.False(m.isFullScreen("vimdiff a b"))
+	assert.False(m.isFullScreen("git add ."))
+}
@@ -1,15 +1,23 @@
 package prompt
 
 import (
+	"context"
 	"github.com/gdamore/tcell"
 	"github.com/jumale/gooster/pkg/command"
+	"github.com/jumale/gooster/pkg/completion"
+	"github.com/jumale/gooster/pkg/config"
+	"github.com/jumale/gooster/pkg/dialog"
 	"github.com/jumale/gooster/pkg/gooster"
+	"github.com/jumale/gooster/pkg/gooster/module/output"
 	"github.com/jumale/gooster/pkg/gooster/module/workdir"
-	"regexp"
+	"github.com/jumale/gooster/pkg/history"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"time"
 )
 
 func (m *Module) handleEventSetPrompt(event EventSetPrompt) {
-	m.view.SetText(event.Input)
+	m.setInput(event.Input)
 	if event.Focus {
 		m.Events().Dispatch(gooster.EventSetFocus{Target: m.view})
 	}
@@ -19,18 +27,25 @@ func (m *Module) handleEventClearPrompt() {
 	m.clearPrompt()
 }
 
-var ignoreCommandErrors = regexList{
-	regexp.MustCompile("^exit status"),
-	regexp.MustCompile("^signal: killed"),
-}
-
 func (m *Module) handleEventExecCommand(event EventExecCommand) {
-	if m.cmd != nil {
-		m.Log().ErrorF("Previous command '%s' is still running. Wait for it to finish or cancel it.", m.cmd.Command())
+	if m.execBuiltin(event.Cmd) {
 		return
 	}
 
-	m.history.Add(event.Cmd)
+	cmd, background := detectBackground(event.Cmd)
+	if fg := m.jobs.Foreground(); fg != nil && !background {
+		m.Log().ErrorF("Previous command '%s' is still running. Wait for it to finish, cancel it, or move it to background.", fg.Cmd)
+		return
+	}
+
+	_, workDir := m.state()
+	// the ignore rules are checked against the input as it has been typed (e.g. with the leading space)
+	var entry *history.Entry
+	if !m.history.Ignores(event.Cmd) {
+		line := historyLine(event.Cmd)
+		m.history.Add(line)
+		entry = &history.Entry{Cmd: line, Dir: workDir, Session: m.session}
+	}
 
 	if m.cfg.PrintCommand {
 		m.Output().WriteF("[%s]%s%s[-]\n", getColorName(m.cfg.Colors.Command.Origin()), m.cfg.Label, event.Cmd)
@@ -42,68 +57,332 @@ func (m *Module) handleEventExecCommand(event EventExecCommand) {
 	}
 	m.clearPrompt()
 
-	// If it looks like "cd" command:
-	if path := detectWorkDirPath(m.Fs(), event.Cmd); path != "" {
-		m.Events().Dispatch(workdir.EventChangeDir{Path: path})
-		return
+	// If it looks like a path to a directory, then "cd" to it
+	if path := detectWorkDirPath(m.Fs(), workDir, cmd); path != "" {
+		cmd = "cd " + shellQuote(path)
 	}
 
-	m.cmd = NewCommand(event.Cmd).SetOutput(m.Output())
-	go func() {
-		m.Log().DebugF("Starting command `%s`", event.Cmd)
-		if err := m.cmd.Run(); err != nil {
-			if !ignoreCommandErrors.MatchString(err.Error()) {
-				m.Log().Error(err)
-			}
-		}
-		m.Log().DebugF("Command finished `%s`", event.Cmd)
-		m.clearCommand()
-	}()
+	shell, _ := m.state()
+	if background {
+		// background jobs must not block the main shell session
+		shell = m.newShell()
+	}
+
+	job := m.jobs.Add(cmd, shell)
+	// full-screen apps can be recognized by switching to the alternate screen,
+	// but the known ones get the terminal right away
+	if !background {
+		m.watchScreen(job)
+	}
+	fullScreen := !background && m.isFullScreen(cmd)
+	if fullScreen {
+		job.command.screen.hold()
+	}
+
+	if background {
+		m.Output().WriteF("[%d] %s\n", job.ID, job.Cmd)
+	} else {
+		m.Events().Dispatch(output.EventOpenBlock{Cmd: job.Cmd})
+		m.jobs.SetForeground(job, m.Output())
+	}
+
+	if fullScreen {
+		m.suspendFor(job)
+	}
+	go m.runJob(job, entry, background)
+}
+
+// runJob runs the job and records the finished command to the history entry (unless it's nil).
+func (m *Module) runJob(job *Job, entry *history.Entry, background bool) {
+	m.Log().DebugF("Starting command `%s`", job.Cmd)
+	m.Events().Dispatch(EventJobStarted{ID: job.ID, Cmd: job.Cmd, Background: background})
+
+	res, err := job.command.Run()
+	close(job.done)
+	if err != nil {
+		m.Log().Error(err)
+	}
+	// in case the terminal has never been handed over
+	job.command.screen.release()
+	m.Log().DebugF("Command finished `%s`", job.Cmd)
+
+	if m.jobs.Foreground() == job {
+		m.Events().Dispatch(output.EventCloseBlock{ExitCode: res.ExitCode})
+		m.clearCommand(job)
+	} else {
+		m.Output().WriteF("[%d] Done (exit code %d) %s\n", job.ID, res.ExitCode, job.Cmd)
+	}
+	m.jobs.Remove(job.ID)
+
+	if shell, _ := m.state(); job.shell != shell {
+		m.check(job.shell.Close(), "close job shell")
+	} else if m.setWorkDir(res.WorkDir) {
+		m.Events().Dispatch(workdir.EventChangeDir{Path: res.WorkDir})
+	}
+
+	finishedAt := time.Now()
+	if entry != nil {
+		entry.Time, entry.ExitCode, entry.Duration = job.StartedAt, res.ExitCode, finishedAt.Sub(job.StartedAt)
+		m.history.Record(*entry)
+	}
+
+	m.Events().Dispatch(EventJobFinished{
+		ID:       job.ID,
+		Cmd:      job.Cmd,
+		ExitCode: res.ExitCode,
+		Duration: finishedAt.Sub(job.StartedAt),
+	})
+	m.Events().Dispatch(EventCommandFinished{
+		Cmd:         job.Cmd,
+		ExitCode:    res.ExitCode,
+		Signal:      res.Signal,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  finishedAt,
+		WorkDir:     res.WorkDir,
+		OutputBytes: job.output.Len(),
+	})
 }
 
 const newLine byte = 10
 
 func (m *Module) handleEventSendUserInput(event EventSendUserInput) {
-	if m.cmd == nil {
+	fg := m.jobs.Foreground()
+	if fg == nil {
 		m.Log().Error("Could not send user input - there is no current command running")
 		return
 	}
 	m.clearPrompt()
-	if _, err := m.cmd.Write(append([]byte(event.Input), newLine)); err != nil {
+	if _, err := fg.command.Write(append([]byte(event.Input), newLine)); err != nil {
 		m.Log().Error(err)
 	}
 }
 
+func (m *Module) handleEventSelectJob(event EventSelectJob) {
+	job := m.jobs.Get(event.ID)
+	if job == nil {
+		m.Log().ErrorF("Could not select job [%d]. Not found.", event.ID)
+		return
+	}
+
+	fg := m.jobs.Foreground()
+	if fg == job {
+		return
+	}
+	if fg != nil {
+		m.moveToBackground(fg)
+	}
+
+	m.Output().WriteF("[%d] %s\n", job.ID, job.Cmd)
+	m.jobs.SetForeground(job, m.Output())
+	m.watchScreen(job)
+}
+
+// watchScreen hands the terminal over to the foreground job, when it switches to the alternate screen.
+func (m *Module) watchScreen(job *Job) {
+	job.command.screen.setOnSwitch(func() { m.suspendFor(job) })
+}
+
+func (m *Module) handleEventResize(event output.EventResize) {
+	m.termWidth, m.termHeight = event.Width, event.Height
+	shell, _ := m.state()
+	shell.SetSize(event.Width, event.Height)
+	for _, job := range m.jobs.List() {
+		job.shell.SetSize(event.Width, event.Height)
+	}
+}
+
+func (m *Module) handleEventCopyBlock(event output.EventCopyBlock) {
+	m.Events().Dispatch(EventSetPrompt{Input: event.Text, Focus: true})
+}
+
+func (m *Module) handleEventRerunBlock(event output.EventRerunBlock) {
+	m.Events().Dispatch(EventExecCommand{Cmd: event.Cmd})
+}
+
+// handleEventChangeDir keeps the shell in sync with the work dir,
+// changed by other modules (e.g. by navigating the work dir tree).
+func (m *Module) handleEventChangeDir(event workdir.EventChangeDir) {
+	if !m.setWorkDir(event.Path) {
+		return
+	}
+	shell, _ := m.state()
+	go func() {
+		if _, err := shell.Exec("cd "+shellQuote(event.Path), ioutil.Discard); err != nil {
+			m.Log().Error(errors.WithMessage(err, "change shell work dir"))
+		}
+	}()
+}
+
+// maxHistoryMatches limits the number of the history search results
+const maxHistoryMatches = 100
+
+func (m *Module) handleEventSearchHistory(event EventSearchHistory) {
+	var matches []history.Match
+	matchColor := getColorName(m.cfg.Colors.Match.Origin())
+	m.history.Merge()
+
+	m.Events().Dispatch(gooster.EventOpenDialog{Dialog: dialog.Search{
+		Title: "History",
+		Query: event.Query,
+		Toggles: []dialog.Toggle{
+			{Key: m.cfg.Keys.HistoryInDir, Title: "in dir", On: event.InDir},
+			{Key: m.cfg.Keys.HistorySucceeded, Title: "succeeded", On: event.Succeeded},
+		},
+		Find: func(query string, toggles []bool) []string {
+			var filters []history.EntryFilter
+			if toggles[0] {
+				_, workDir := m.state()
+				filters = append(filters, history.InDir(workDir))
+			}
+			if toggles[1] {
+				filters = append(filters, history.Successful())
+			}
+			matches = m.history.Search(query, filters...)
+			if len(matches) > maxHistoryMatches {
+				matches = matches[:maxHistoryMatches]
+			}
+			items := make([]string, len(matches))
+			for i, match := range matches {
+				items[i] = highlightMatch(match, matchColor)
+			}
+			return items
+		},
+		Actions: map[config.Key]func(index int){
+			config.NewKey(tcell.KeyEnter): func(index int) {
+				m.Events().Dispatch(EventExecCommand{Cmd: matches[index].Cmd})
+			},
+			m.cfg.Keys.HistoryEdit: func(index int) {
+				m.Events().Dispatch(EventSetPrompt{Input: matches[index].Cmd, Focus: true})
+			},
+		},
+		Next:     []config.Key{m.cfg.Keys.HistorySearch},
+		Selected: m.cfg.Colors.Selected,
+		Log:      m.Log(),
+	}})
+}
+
 func (m *Module) handleEventInterruptCommand() {
 	m.clearPrompt()
-	if m.cmd == nil {
+	fg := m.jobs.Foreground()
+	if fg == nil {
 		return
 	}
-	m.cmd.Cancel()
-	m.clearCommand()
+	m.check(fg.command.Cancel(), "interrupt command")
 }
 
-func (m *Module) handleKeyHistoryPrev(event *tcell.EventKey) *tcell.EventKey {
-	if !m.history.IsActive() {
-		m.latestInput = m.view.GetText()
+func (m *Module) handleEventExit() {
+	for _, job := range m.jobs.List() {
+		m.check(job.shell.Close(), "close job shell")
 	}
-	m.Events().Dispatch(EventSetPrompt{Input: m.history.Prev()})
-	return event
+	shell, _ := m.state()
+	m.check(shell.Close(), "close shell")
+}
+
+func (m *Module) handleKeyHistoryPrev(filter HistoryFilter) gooster.KeyEventHandler {
+	return func(event *tcell.EventKey) *tcell.EventKey {
+		if !m.history.IsActive() {
+			m.latestInput = m.view.Input()
+			// pick up the commands of other running instances and shells
+			m.history.Merge()
+		}
+		// the typed text is kept, if there are no matching entries
+		if input, ok := m.history.PrevMatch(m.historyFilter(filter)); ok {
+			m.Events().Dispatch(EventSetPrompt{Input: input})
+		}
+		return event
+	}
+}
+
+func (m *Module) handleKeyHistoryNext(filter HistoryFilter) gooster.KeyEventHandler {
+	return func(event *tcell.EventKey) *tcell.EventKey {
+		if !m.history.IsActive() {
+			return event
+		}
+		input, ok := m.history.NextMatch(m.historyFilter(filter))
+		if !ok {
+			input = m.latestInput
+		}
+		m.Events().Dispatch(EventSetPrompt{Input: input})
+		return event
+	}
+}
+
+// historyFilter selects the history entries by the text, which has been typed before the navigation.
+func (m *Module) historyFilter(filter HistoryFilter) history.Filter {
+	if m.latestInput == "" {
+		return nil
+	}
+	switch filter {
+	case HistoryPrefix:
+		return history.Prefix(m.latestInput)
+	case HistorySubstring:
+		return history.Contains(m.latestInput)
+	default:
+		return nil
+	}
+}
+
+func (m *Module) handleKeyHistorySearch(_ *tcell.EventKey) *tcell.EventKey {
+	m.Events().Dispatch(EventSearchHistory{Query: m.view.Input()})
+	return nil
 }
 
-func (m *Module) handleKeyHistoryNext(event *tcell.EventKey) *tcell.EventKey {
-	input := m.history.Next()
-	if !m.history.IsActive() {
-		input = m.latestInput
+// handleKeyMultiLine starts a new line of the input, or joins the empty line with the previous one on Backspace.
+func (m *Module) handleKeyMultiLine(event *tcell.EventKey) *tcell.EventKey {
+	for _, key := range m.cfg.Keys.NewLine {
+		if key.Matches(event) {
+			m.newLine()
+			return nil
+		}
+	}
+	isBackspace := event.Key() == tcell.KeyBackspace || event.Key() == tcell.KeyBackspace2
+	if isBackspace && m.view.GetText() == "" && m.view.JoinLine() {
+		m.updateHeight()
+		return nil
 	}
-	m.Events().Dispatch(EventSetPrompt{Input: input})
 	return event
 }
 
+func (m *Module) handleKeyBackground(event *tcell.EventKey) *tcell.EventKey {
+	m.Events().Dispatch(EventExecCommand{Cmd: "bg"})
+	return nil
+}
+
+// handleCompletion requests the completion on another goroutine, since it could take a while,
+// and applies it on the UI goroutine.
 func (m *Module) handleCompletion(input string) {
 	commands, err := command.ParseCommands(input)
 	if err != nil {
 		m.Log().DebugF("ParseCommands error: %s", err)
 	}
-	m.Events().Dispatch(gooster.EventSetCompletion{Input: input, Commands: commands})
+
+	_, workDir := m.state()
+	query := gooster.QueryCompletion{
+		Input:    input,
+		Commands: commands,
+		WorkDir:  workDir,
+	}
+	go func() {
+		result, err := m.Events().Request(context.Background(), query)
+		if err != nil {
+			m.Log().DebugF("Completion error: %s", err)
+		}
+		compl, _ := result.(completion.Completion)
+		m.Events().Dispatch(gooster.EventQueueUpdate{Update: func() {
+			m.applyCompletion(input, commands, compl)
+		}})
+	}()
+}
+
+func (m *Module) applyCompletion(input string, commands []command.Definition, compl completion.Completion) {
+	if m.view.Input() != input {
+		// the input has been changed, while the completion was requested
+		return
+	}
+	if compl.IsUnique() {
+		// there is no need to show the unique completion to the user
+		m.setInput(compl.SelectFirst().ApplyTo(input))
+		return
+	}
+	m.Events().Dispatch(gooster.EventSetCompletion{Input: input, Commands: commands, Completion: compl})
 }
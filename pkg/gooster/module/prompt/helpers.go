@@ -2,8 +2,12 @@ package prompt
 
 import (
 	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/command"
 	"github.com/jumale/gooster/pkg/filesys"
+	"github.com/jumale/gooster/pkg/history"
 	"github.com/pkg/errors"
+	"github.com/rivo/tview"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
@@ -30,10 +34,12 @@ func (m *Module) check(err error, msg ...string) {
 
 var pathRegex = regexp.MustCompile(`^(?:(?:\.{1,2}/)|(?:(?:/[^/]+)+))`)
 
-func detectWorkDirPath(fs filesys.FileSys, command string) (path string) {
+// detectWorkDirPath returns the directory, which the command changes to,
+// resolving relative paths against the work dir of the prompt.
+func detectWorkDirPath(fs filesys.FileSys, workDir string, command string) (path string) {
 	args := strings.Split(command, " ")
 
-	if args[0] == "cd" && len(args) >= 2 {
+	if args[0] == "cd" && len(args) == 2 {
 		path = args[1]
 	} else if pathRegex.MatchString(args[0]) {
 		path = args[0]
@@ -48,13 +54,8 @@ func detectWorkDirPath(fs filesys.FileSys, command string) (path string) {
 		path = strings.Replace(path, "~", ud, 1)
 	}
 
-	if strings.HasPrefix(path, "./") {
-		path = strings.Replace(path, "./", "", 1)
-	}
-
-	if !strings.HasPrefix(path, "/") {
-		wd, _ := fs.Getwd()
-		path = strings.Replace(wd+"/"+path, "//", "/", -1)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
 	}
 
 	// return empty if it's not a directory
@@ -66,13 +67,140 @@ func detectWorkDirPath(fs filesys.FileSys, command string) (path string) {
 	return path
 }
 
-type regexList []*regexp.Regexp
+// shellQuote wraps the value into single quotes, so that bash treats it literally.
+func shellQuote(val string) string {
+	return "'" + strings.Replace(val, "'", `'\''`, -1) + "'"
+}
+
+// highlightMatch marks the matched characters of the history search result by the color.
+func highlightMatch(match history.Match, color string) string {
+	var buf strings.Builder
+	var segment strings.Builder
+	matched := false
+	flush := func() {
+		if segment.Len() == 0 {
+			return
+		}
+		if matched {
+			buf.WriteString("[" + color + "]" + tview.Escape(segment.String()) + "[-]")
+		} else {
+			buf.WriteString(tview.Escape(segment.String()))
+		}
+		segment.Reset()
+	}
+
+	positions := make(map[int]bool, len(match.Positions))
+	for _, pos := range match.Positions {
+		positions[pos] = true
+	}
+	for i, r := range match.Cmd {
+		if positions[i] != matched {
+			flush()
+			matched = positions[i]
+		}
+		segment.WriteRune(r)
+	}
+	flush()
+	return buf.String()
+}
+
+// incompleteInput tells whether the input must be continued on the next line:
+// it has non closed quotes, a trailing backslash, a non closed compound command or heredoc.
+func incompleteInput(input string) bool {
+	if strings.HasSuffix(input, `\`) {
+		return true
+	}
+	lines, err := command.SplitLines(input)
+	if err != nil {
+		// non closed quotes or heredocs
+		return true
+	}
 
-func (r regexList) MatchString(s string) bool {
-	for _, pattern := range r {
-		if pattern.MatchString(s) {
+	// the lines are separate commands, so that the keywords are found at the command position
+	commandLines := make([]string, len(lines))
+	for i, line := range lines {
+		commandLines[i] = line.Command
+	}
+	commands, _ := command.ParseCommands(strings.Join(commandLines, ";"))
+	depth := 0
+	for _, cmd := range commands {
+		switch cmd.Command {
+		case "if", "case", "for", "while", "until", "select", "{":
+			depth++
+		case "fi", "esac", "done", "}":
+			depth--
+		default:
+			if functionHeader(cmd) {
+				depth++
+			}
+		}
+	}
+	return depth > 0
+}
+
+// functionHeader tells whether the command starts a function definition (e.g. "foo() {" or "function foo {").
+// The braces in arguments of other commands are just words (e.g. "echo {").
+func functionHeader(cmd command.Definition) bool {
+	words := append([]string{cmd.Command}, cmd.Args...)
+	if words[len(words)-1] != "{" {
+		return false
+	}
+	keyword := words[0] == "function"
+	name := words[:len(words)-1]
+	if keyword {
+		name = name[1:]
+	}
+
+	switch len(name) {
+	case 1:
+		return keyword || strings.HasSuffix(name[0], "()")
+	case 2:
+		return name[1] == "()"
+	}
+	return false
+}
+
+// historyLine joins the lines of a multi-line command into a single line for the history,
+// the same way as bash does it. The line breaks within quotes and heredocs are kept.
+func historyLine(cmd string) string {
+	lines, err := command.SplitLines(cmd)
+	if err != nil {
+		return cmd
+	}
+
+	var buf strings.Builder
+	separator := ""
+	for _, line := range lines {
+		text := strings.TrimSpace(line.Command)
+		if text == "" {
+			continue
+		}
+		buf.WriteString(separator)
+		switch {
+		case line.Heredoc != "":
+			// the content of heredocs can't be joined
+			text, separator = text+"\n"+line.Heredoc, "\n"
+		case joinsWithSpace(text):
+			separator = " "
+		default:
+			separator = "; "
+		}
+		buf.WriteString(text)
+	}
+	return buf.String()
+}
+
+// joinsWithSpace tells whether the line is continued by the next one without a command separator.
+func joinsWithSpace(line string) bool {
+	for _, suffix := range []string{"|", "&&", "||", ";", "&"} {
+		if strings.HasSuffix(line, suffix) {
 			return true
 		}
 	}
+	fields := strings.Fields(line)
+	switch fields[len(fields)-1] {
+	case "do", "then", "else", "in", "{":
+		return true
+	}
 	return false
 }
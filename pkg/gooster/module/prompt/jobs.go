@@ -0,0 +1,174 @@
+package prompt
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job is a command, running in its own shell session.
+type Job struct {
+	ID        int
+	Cmd       string
+	StartedAt time.Time
+	command   *Command
+	shell     *Shell
+	output    *jobOutput
+	done      chan struct{}
+}
+
+// Output returns everything the job has printed so far.
+func (j *Job) Output() []byte {
+	return j.output.Bytes()
+}
+
+type JobManager struct {
+	mu     *sync.Mutex
+	jobs   map[int]*Job
+	fg     *Job
+	lastID int
+}
+
+func NewJobManager() *JobManager {
+	return &JobManager{mu: &sync.Mutex{}, jobs: make(map[int]*Job)}
+}
+
+// Add registers a new job for the command, which will be executed in the provided shell.
+func (jm *JobManager) Add(cmd string, shell *Shell) *Job {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	jm.lastID++
+	out := newJobOutput()
+	job := &Job{
+		ID:        jm.lastID,
+		Cmd:       cmd,
+		StartedAt: time.Now(),
+		command:   NewCommand(cmd, shell).SetOutput(out),
+		shell:     shell,
+		output:    out,
+		done:      make(chan struct{}),
+	}
+	jm.jobs[job.ID] = job
+	return job
+}
+
+func (jm *JobManager) Remove(id int) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	if jm.fg != nil && jm.fg.ID == id {
+		jm.fg = nil
+	}
+	delete(jm.jobs, id)
+	// like in bash, numbering starts over when there are no jobs left
+	if len(jm.jobs) == 0 {
+		jm.lastID = 0
+	}
+}
+
+func (jm *JobManager) Get(id int) *Job {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	return jm.jobs[id]
+}
+
+// Foreground returns the job, which receives user input and streams its output to the app,
+// or nil if all jobs are running in background.
+func (jm *JobManager) Foreground() *Job {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	return jm.fg
+}
+
+// SetForeground brings the job to foreground and streams its output (including
+// everything it has printed while being in background) to the target.
+// The previous foreground job is moved to background.
+// Passing nil job just moves the current one to background.
+func (jm *JobManager) SetForeground(job *Job, target io.Writer) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	if jm.fg != nil {
+		jm.fg.output.detach()
+	}
+	jm.fg = job
+	if job != nil {
+		job.output.attach(target)
+	}
+}
+
+// List returns all running jobs ordered by ID.
+func (jm *JobManager) List() []*Job {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	list := make([]*Job, 0, len(jm.jobs))
+	for _, job := range jm.jobs {
+		list = append(list, job)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// jobOutput buffers the whole output of a job,
+// and streams it to the target while the job is in foreground.
+type jobOutput struct {
+	mu     *sync.Mutex
+	buf    []byte
+	shown  int
+	target io.Writer
+}
+
+func newJobOutput() *jobOutput {
+	return &jobOutput{mu: &sync.Mutex{}}
+}
+
+func (o *jobOutput) Write(p []byte) (n int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.buf = append(o.buf, p...)
+	if o.target == nil {
+		return len(p), nil
+	}
+	o.shown = len(o.buf)
+	return o.target.Write(p)
+}
+
+func (o *jobOutput) Bytes() []byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]byte{}, o.buf...)
+}
+
+func (o *jobOutput) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.buf)
+}
+
+func (o *jobOutput) attach(target io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.shown < len(o.buf) {
+		_, _ = target.Write(o.buf[o.shown:])
+		o.shown = len(o.buf)
+	}
+	o.target = target
+}
+
+func (o *jobOutput) detach() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.target = nil
+}
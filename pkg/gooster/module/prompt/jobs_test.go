@@ -0,0 +1,94 @@
+package prompt
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestJobManager(t *testing.T) {
+	assert := require.New(t)
+	shell := NewShell(ShellConfig{})
+
+	t.Run("should assign incremental IDs and start over when there are no jobs", func(t *testing.T) {
+		jm := NewJobManager()
+		assert.Equal(1, jm.Add("foo", shell).ID)
+		assert.Equal(2, jm.Add("bar", shell).ID)
+
+		jm.Remove(1)
+		assert.Equal(3, jm.Add("baz", shell).ID)
+
+		jm.Remove(2)
+		jm.Remove(3)
+		assert.Equal(1, jm.Add("qux", shell).ID)
+	})
+
+	t.Run("should list jobs ordered by ID", func(t *testing.T) {
+		jm := NewJobManager()
+		jm.Add("foo", shell)
+		jm.Add("bar", shell)
+		jm.Add("baz", shell)
+		jm.Remove(2)
+
+		var cmds []string
+		for _, job := range jm.List() {
+			cmds = append(cmds, job.Cmd)
+		}
+		assert.Equal([]string{"foo", "baz"}, cmds)
+	})
+
+	t.Run("should stream output of the foreground job only", func(t *testing.T) {
+		jm := NewJobManager()
+		foo := jm.Add("foo", shell)
+		bar := jm.Add("bar", shell)
+		target := bytes.NewBuffer(nil)
+
+		jm.SetForeground(foo, target)
+		_, _ = foo.output.Write([]byte("foo1\n"))
+		_, _ = bar.output.Write([]byte("bar1\n"))
+		assert.Equal("foo1\n", target.String())
+		assert.Equal(foo, jm.Foreground())
+
+		t.Run("and flush the output buffered in background, when the job is selected", func(t *testing.T) {
+			jm.SetForeground(bar, target)
+			_, _ = foo.output.Write([]byte("foo2\n"))
+			_, _ = bar.output.Write([]byte("bar2\n"))
+
+			assert.Equal("foo1\nbar1\nbar2\n", target.String())
+			assert.Equal("foo1\nfoo2\n", string(foo.Output()))
+			assert.Equal("bar1\nbar2\n", string(bar.Output()))
+		})
+
+		t.Run("and reset foreground job when it's removed", func(t *testing.T) {
+			jm.Remove(bar.ID)
+			assert.Nil(jm.Foreground())
+		})
+	})
+}
+
+func TestDetectBackground(t *testing.T) {
+	assert := require.New(t)
+
+	cmd, bg := detectBackground("sleep 10 &")
+	assert.True(bg)
+	assert.Equal("sleep 10", cmd)
+
+	cmd, bg = detectBackground("make && make install")
+	assert.False(bg)
+	assert.Equal("make && make install", cmd)
+
+	cmd, bg = detectBackground("ls 2>&1")
+	assert.False(bg)
+	assert.Equal("ls 2>&1", cmd)
+}
+
+func TestSimpleCommand(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Equal([]string{"fg", "%2"}, simpleCommand("fg %2"))
+	assert.Equal([]string{"jobs"}, simpleCommand(" jobs"))
+
+	for _, input := range []string{"", "jobs | grep x", "fg; ls", "bg && echo", "jobs > file", "kill %1 &", "jobs\nls"} {
+		assert.Nil(simpleCommand(input), input)
+	}
+}
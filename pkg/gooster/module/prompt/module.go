@@ -1,40 +1,81 @@
 package prompt
 
 import (
+	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/jumale/gooster/pkg/config"
 	"github.com/jumale/gooster/pkg/events"
 	"github.com/jumale/gooster/pkg/gooster"
 	"github.com/jumale/gooster/pkg/history"
-	"github.com/rivo/tview"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// sessionCount numbers the prompt instances (e.g. in tabs and panes) of the process
+var sessionCount int32
+
 type Module struct {
 	gooster.Context
-	cfg         Config
-	view        *tview.InputField
-	history     *history.Manager
-	cmd         *Command
+	cfg  Config
+	view *editor
+	// height is the number of the visible lines of the prompt
+	height  int
+	history *history.Manager
+	jobs    *JobManager
+	// mu guards the main shell session and the work dir, which are updated by the jobs finished on their goroutines
+	mu      *sync.Mutex
+	shell   *Shell
+	workDir string
+	// session identifies the commands of the prompt instance in the history
+	session     string
 	latestInput string
+	termWidth   int
+	termHeight  int
 }
 
 func NewModule() *Module {
-	return &Module{cfg: Config{
-		Label:        " > ",
-		PrintDivider: true,
-		PrintCommand: true,
-		HistoryFile:  "~/.bash_history",
+	return &Module{mu: &sync.Mutex{}, cfg: Config{
+		Label:               " > ",
+		PrintDivider:        true,
+		PrintCommand:        true,
+		HistoryFile:         "~/.bash_history",
+		ExtendedHistoryFile: "~/.gooster/history.jsonl",
+		HistorySize:         10000,
+		HistoryIgnoreSpace:  true,
+		HistoryIgnore: []string{
+			`(?i)(password|passwd|secret|token|api_?key)=\S+`,
+		},
+		MaxHeight: 10,
+		Shell:     "bash",
+		ShellArgs: []string{"-l"},
+		FullScreen: []string{
+			"vi", "vim", "nvim", "nano", "emacs", "less", "more", "man",
+			"top", "htop", "fzf", "ssh", "tmux", "screen", "git add -p",
+		},
 		Colors: ColorsConfig{
-			Bg:      config.Color(tcell.NewHexColor(0x555555)),
-			Label:   config.Color(tcell.ColorLime),
-			Text:    config.Color(tcell.ColorLightGray),
-			Divider: config.Color(tcell.ColorLightGreen),
-			Command: config.Color(tcell.ColorLightSkyBlue),
+			Bg:       config.Color(tcell.NewHexColor(0x555555)),
+			Label:    config.Color(tcell.ColorLime),
+			Text:     config.Color(tcell.ColorLightGray),
+			Divider:  config.Color(tcell.ColorLightGreen),
+			Command:  config.Color(tcell.ColorLightSkyBlue),
+			Match:    config.Color(tcell.ColorYellow),
+			Selected: config.Color(tcell.ColorDarkSlateGray),
 		},
 		Keys: KeysConfig{
-			HistoryNext: config.NewKey(tcell.KeyDown),
-			HistoryPrev: config.NewKey(tcell.KeyUp),
+			HistoryNext:      config.NewKey(tcell.KeyDown),
+			HistoryPrev:      config.NewKey(tcell.KeyUp),
+			HistoryFilter:    HistoryPrefix,
+			Background:       config.NewKey(tcell.KeyCtrlZ),
+			HistorySearch:    config.NewKey(tcell.KeyCtrlR),
+			HistoryEdit:      config.NewKey(tcell.KeyTab),
+			HistoryInDir:     config.NewKey(tcell.KeyCtrlD),
+			HistorySucceeded: config.NewKey(tcell.KeyCtrlS),
+			NewLine: []config.Key{
+				config.NewKey(tcell.KeyEnter).AddMod(tcell.ModAlt),
+				config.NewKey(tcell.KeyEnter).AddMod(tcell.ModShift),
+			},
 		},
 	}}
 }
@@ -54,16 +95,25 @@ func (m *Module) Init(ctx gooster.Context) (err error) {
 	}
 
 	m.history, err = history.NewManager(history.Config{
-		HistoryFile: m.cfg.HistoryFile,
-		Log:         ctx.Log(),
-		FileSys:     ctx.Fs(),
+		HistoryFile:  m.cfg.HistoryFile,
+		ExtendedFile: m.cfg.ExtendedHistoryFile,
+		MaxSize:      m.cfg.HistorySize,
+		IgnoreSpace:  m.cfg.HistoryIgnoreSpace,
+		Ignore:       m.cfg.HistoryIgnore,
+		Log:          ctx.Log(),
+		FileSys:      ctx.Fs(),
 	})
 	if err != nil {
 		return err
 	}
+	m.session = fmt.Sprintf("%d-%d", os.Getpid(), atomic.AddInt32(&sessionCount, 1))
+
+	workDir, _ := ctx.Fs().Getwd()
+	m.setWorkDir(workDir)
+	m.setShell(m.newShell())
+	m.jobs = NewJobManager()
 
-	m.view = tview.NewInputField()
-	m.view.SetLabel(m.cfg.Label)
+	m.view = newEditor(m.cfg.Label, m.cfg.Colors)
 	m.view.SetFieldWidth(m.cfg.FieldWidth)
 	m.view.SetBorder(false)
 	m.view.SetLabelColor(m.cfg.Colors.Label.Origin())
@@ -71,39 +121,43 @@ func (m *Module) Init(ctx gooster.Context) (err error) {
 	m.view.SetFieldBackgroundColor(m.cfg.Colors.Bg.Origin())
 	m.view.SetFieldTextColor(m.cfg.Colors.Text.Origin())
 
-	m.Events().Subscribe(events.HandleFunc(func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case EventSetPrompt:
-			m.handleEventSetPrompt(event)
-		case EventClearPrompt:
-			m.handleEventClearPrompt()
-		case EventExecCommand:
-			m.handleEventExecCommand(event)
-		case EventSendUserInput:
-			m.handleEventSendUserInput(event)
-			m.Events().Dispatch(gooster.EventOutput{Data: []byte(event.Input + "\n")})
-		case gooster.EventInterrupt:
-			m.handleEventInterruptCommand()
-		case gooster.EventSetCompletion:
-			if event.Completion.IsUnique() {
-				m.view.SetText(event.Completion.SelectFirst().ApplyTo(m.view.GetText()))
-				return events.StopPropagation
-			}
-		}
-		return e
-	}))
+	m.Events().Subscribe(
+		events.On(m.handleEventSetPrompt),
+		events.On(func(EventClearPrompt) { m.handleEventClearPrompt() }),
+		events.On(m.handleEventExecCommand),
+		events.On(m.handleEventSendUserInput),
+		events.On(m.handleEventSelectJob),
+		events.On(m.handleEventResize),
+		events.On(m.handleEventCopyBlock),
+		events.On(m.handleEventRerunBlock),
+		events.On(m.handleEventChangeDir),
+		events.On(m.handleEventSearchHistory),
+		events.On(func(gooster.EventInterrupt) { m.handleEventInterruptCommand() }),
+		events.On(func(gooster.EventExit) { m.handleEventExit() }),
+		events.On(func(gooster.EventTabClosed) { m.handleEventExit() }),
+		events.On(func(gooster.EventPaneClosed) { m.handleEventExit() }),
+	)
 
-	gooster.HandleKeyEvents(m.view, gooster.KeyEventHandlers{
-		m.cfg.Keys.HistoryPrev: m.handleKeyHistoryPrev,
-		m.cfg.Keys.HistoryNext: m.handleKeyHistoryNext,
-	})
+	keys := gooster.KeyEventHandlers{
+		m.cfg.Keys.HistoryPrev:   m.handleKeyHistoryPrev(m.cfg.Keys.HistoryFilter),
+		m.cfg.Keys.HistoryNext:   m.handleKeyHistoryNext(m.cfg.Keys.HistoryFilter),
+		m.cfg.Keys.Background:    m.handleKeyBackground,
+		m.cfg.Keys.HistorySearch: m.handleKeyHistorySearch,
+	}
+	for _, binding := range m.cfg.Keys.HistoryBindings {
+		keys[binding.Prev] = m.handleKeyHistoryPrev(binding.Filter)
+		keys[binding.Next] = m.handleKeyHistoryNext(binding.Filter)
+	}
+	// the keys are matched regardless of the runes, which some terminals send with Enter and Backspace
+	m.view.SetInputCapture(m.handleKeyMultiLine)
+	gooster.HandleKeyEvents(m.view, keys)
 
 	m.view.SetDoneFunc(m.submit)
 	return nil
 }
 
 func (m *Module) submit(key tcell.Key) {
-	input := m.view.GetText()
+	input := m.view.Input()
 	if input == "" {
 		return
 	}
@@ -112,7 +166,10 @@ func (m *Module) submit(key tcell.Key) {
 		m.handleCompletion(input)
 
 	case tcell.KeyEnter:
-		if m.cmd == nil {
+		if m.jobs.Foreground() == nil && incompleteInput(input) {
+			// the command continues on the next line
+			m.newLine()
+		} else if m.jobs.Foreground() == nil {
 			m.Events().Dispatch(EventExecCommand{Cmd: input})
 		} else {
 			m.Events().Dispatch(EventSendUserInput{Input: input})
@@ -120,13 +177,12 @@ func (m *Module) submit(key tcell.Key) {
 	}
 }
 
-func (m *Module) clearCommand() {
+func (m *Module) clearCommand(job *Job) {
 	lineBreak := ""
-	if m.cmd != nil && m.cmd.LastChar() != newLine {
+	if job.command.LastChar() != newLine {
 		lineBreak = "\n"
 	}
 
-	m.cmd = nil
 	if m.cfg.PrintDivider {
 		_, _, width, _ := m.view.GetInnerRect()
 		m.Output().WriteF(
@@ -138,7 +194,81 @@ func (m *Module) clearCommand() {
 	}
 }
 
+// newShell creates a shell session in the work dir, which continues the state of the main session (if any).
+func (m *Module) newShell() *Shell {
+	shell, workDir := m.state()
+	var state []byte
+	if shell != nil {
+		var err error
+		state, err = shell.State()
+		m.check(err, "copy shell state")
+	}
+	sh := NewShell(ShellConfig{
+		Bin:    m.cfg.Shell,
+		Args:   m.cfg.ShellArgs,
+		Dir:    workDir,
+		State:  state,
+		Output: &crlfWriter{target: m.Output()},
+	})
+	if m.termWidth > 0 && m.termHeight > 0 {
+		sh.SetSize(m.termWidth, m.termHeight)
+	}
+	return sh
+}
+
+// state returns the main shell session and the work dir.
+func (m *Module) state() (*Shell, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.shell, m.workDir
+}
+
+func (m *Module) setShell(shell *Shell) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shell = shell
+}
+
+// setWorkDir updates the work dir and reports whether it has been changed.
+func (m *Module) setWorkDir(dir string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if dir == "" || dir == m.workDir {
+		return false
+	}
+	m.workDir = dir
+	return true
+}
+
 func (m *Module) clearPrompt() {
-	m.view.SetText("")
+	m.setInput("")
 	m.history.Reset()
 }
+
+// setInput puts the (possibly multi-line) text to the prompt.
+func (m *Module) setInput(text string) {
+	m.view.SetInput(text)
+	m.updateHeight()
+}
+
+func (m *Module) newLine() {
+	m.view.NewLine()
+	m.updateHeight()
+}
+
+// updateHeight grows (or shrinks) the prompt to fit the lines of the input.
+func (m *Module) updateHeight() {
+	height := m.view.Height()
+	if m.cfg.MaxHeight > 0 {
+		height = minInt(height, m.cfg.MaxHeight)
+	}
+	if height == 1 {
+		// the configured height is restored
+		height = 0
+	}
+	if height == m.height {
+		return
+	}
+	m.height = height
+	m.Events().Dispatch(gooster.EventSetModuleHeight{View: m.view, Height: height})
+}
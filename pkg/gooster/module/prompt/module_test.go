@@ -4,7 +4,14 @@ import (
 	"github.com/gdamore/tcell"
 	"github.com/jumale/gooster/pkg/config"
 	"github.com/jumale/gooster/pkg/filesys/fstub"
+	"github.com/jumale/gooster/pkg/gooster/module/output"
+	"github.com/jumale/gooster/pkg/gooster/module/workdir"
 	tools "github.com/jumale/gooster/pkg/gooster/test_tools"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 )
@@ -20,10 +27,13 @@ func TestModule(t *testing.T) {
 		Command: config.Color(tcell.ColorDefault),
 	}
 
+	shellArgs := []string{"--norc", "--noprofile"}
+
 	cfg := Config{
 		Label:      promptLabel,
 		FieldWidth: 0,
 		Colors:     colors,
+		ShellArgs:  shellArgs,
 	}
 
 	init := func(t *testing.T, cfg Config) *tools.ModuleTester {
@@ -50,12 +60,15 @@ func TestModule(t *testing.T) {
 			Label:        promptLabel,
 			PrintCommand: true,
 			PrintDivider: true,
+			ShellArgs:    shellArgs,
 			Colors: ColorsConfig{
 				Divider: config.Color(tcell.ColorRed),
 				Command: config.Color(tcell.ColorBlue),
 			},
 		}
 		module := init(t, cfg)
+		// the divider fits the drawn view
+		module.Draw()
 
 		module.SendEvent(EventExecCommand{Cmd: `echo "foo"`})
 		time.Sleep(100 * time.Millisecond)
@@ -87,6 +100,90 @@ func TestModule(t *testing.T) {
 		module.AssertHasLog("Command finished `bash ./testdata/prompt.sh`")
 	})
 
+	t.Run("should run commands in background", func(t *testing.T) {
+		module := init(t, cfg)
+		module.SendEvent(EventExecCommand{Cmd: "sleep 0.3; echo bar &"})
+		module.SendEvent(EventExecCommand{Cmd: "echo foo"})
+
+		time.Sleep(100 * time.Millisecond)
+		module.SendEvent(EventExecCommand{Cmd: "jobs"})
+		module.AssertOutputHasLines(
+			"[1] sleep 0.3; echo bar",
+			"foo",
+			regexp.MustCompile(`\[1\]  \d+s\s+sleep 0.3; echo bar`),
+		)
+
+		time.Sleep(500 * time.Millisecond)
+		module.AssertOutputHasLines("[1] Done (exit code 0) sleep 0.3; echo bar")
+		module.AssertHasLog("Command finished `sleep 0.3; echo bar`")
+	})
+
+	t.Run("should apply the results of the jobs finished while running others", func(t *testing.T) {
+		prompt := NewModule()
+		module := tools.NewModuleTester(t, prompt, cfg)
+		module.AssertInited()
+		tmp := os.TempDir()
+		module.SendEvent(EventExecCommand{Cmd: "sleep 0.1 &"})
+		module.SendEvent(EventExecCommand{Cmd: "cd " + tmp})
+
+		time.Sleep(100 * time.Millisecond)
+		module.SendEvent(EventExecCommand{Cmd: "sleep 0.2; pwd"})
+		module.SendEvent(EventExecCommand{Cmd: "bg"})
+		module.SendEvent(EventExecCommand{Cmd: "pwd &"})
+		module.SendEvent(output.EventResize{Width: 80, Height: 24})
+
+		time.Sleep(500 * time.Millisecond)
+		module.AssertOutputHasLines("[1] Done (exit code 0) sleep 0.1")
+		module.AssertOutputHasLines("[3] Done (exit code 0) sleep 0.2; pwd")
+		module.AssertOutputHasLines("[4] Done (exit code 0) pwd")
+
+		_, workDir := prompt.state()
+		require.Equal(t, tmp, workDir)
+	})
+
+	t.Run("should change to the relative dirs of its own work dir", func(t *testing.T) {
+		tmp, err := ioutil.TempDir("", "prompt")
+		require.NoError(t, err)
+		defer func() { require.NoError(t, os.RemoveAll(tmp)) }()
+
+		var prompts []*Module
+		for _, name := range []string{"foo", "bar"} {
+			dir := filepath.Join(tmp, name)
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+
+			prompt := NewModule()
+			module := tools.NewModuleTester(t, prompt, cfg)
+			module.AssertInited()
+			module.Fs.Root().Add(filepath.Join(dir, "sub"), fstub.NewDir())
+			module.SendEvent(workdir.EventChangeDir{Path: dir})
+			module.SendEvent(EventExecCommand{Cmd: "./sub"})
+			prompts = append(prompts, prompt)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		_, workDir := prompts[0].state()
+		require.Equal(t, filepath.Join(tmp, "foo", "sub"), workDir)
+		_, workDir = prompts[1].state()
+		require.Equal(t, filepath.Join(tmp, "bar", "sub"), workDir)
+	})
+
+	t.Run("should put the copied block to the prompt as it is", func(t *testing.T) {
+		prompt := NewModule()
+		module := tools.NewModuleTester(t, prompt, cfg)
+		module.AssertInited()
+
+		module.SendEvent(output.EventCopyBlock{Text: "echo foo\necho bar"})
+		require.Equal(t, "echo foo\necho bar", prompt.view.Input())
+	})
+
+	t.Run("should pass the builtins within pipes and lists to the shell", func(t *testing.T) {
+		module := init(t, cfg)
+		module.SendEvent(EventExecCommand{Cmd: "jobs | wc -l"})
+
+		time.Sleep(100 * time.Millisecond)
+		module.AssertHasLog("Command finished `jobs | wc -l`")
+	})
+
 	cfgWithHistory := Config{
 		Label:       promptLabel,
 		Colors:      colors,
@@ -121,6 +218,30 @@ func TestModule(t *testing.T) {
 		module.AssertView(withLabel("init"))
 	})
 
+	t.Run("should navigate history entries starting with the typed text", func(t *testing.T) {
+		cfg := cfgWithHistory
+		cfg.Keys.HistoryFilter = HistoryPrefix
+		module := tools.NewModuleTester(t, NewModule(), cfg)
+		module.SetSize(10, 1)
+		module.Fs.Root().Add("/history", fstub.NewFile("bar", "foo", "baz"))
+		module.AssertInited()
+
+		module.Draw()
+		module.SendEvent(EventSetPrompt{Input: "ba"})
+
+		module.PressKey(cfg.Keys.HistoryPrev.Type)
+		module.AssertView(withLabel("baz"))
+
+		module.PressKey(cfg.Keys.HistoryPrev.Type)
+		module.AssertView(withLabel("bar"))
+
+		module.PressKey(cfg.Keys.HistoryNext.Type)
+		module.AssertView(withLabel("baz"))
+
+		module.PressKey(cfg.Keys.HistoryNext.Type)
+		module.AssertView(withLabel("ba"))
+	})
+
 	t.Run("should use configured colors", func(t *testing.T) {
 		cfg := Config{
 			Label:      promptLabel,
@@ -0,0 +1,430 @@
+package prompt
+
+import (
+	"bytes"
+	"github.com/creack/pty"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+type ShellConfig struct {
+	Bin  string
+	Args []string
+	// Dir is the work dir of the shell, the process work dir is used if it's empty
+	Dir string
+	// State is the state of another session (see Shell.State), which is restored before the first command
+	State []byte
+	// Output receives everything the shell prints while no command is running
+	// (e.g. output of the background processes).
+	Output io.Writer
+}
+
+// Result describes the state of the shell after a command has finished.
+type Result struct {
+	ExitCode int
+	// Signal is the signal, which has terminated the shell, taken from its wait status.
+	// Bash reports a command terminated by a signal with exit code 128+N, which doesn't tell it
+	// apart from a command exited with the same code (e.g. `exit 130`), so such commands have no signal.
+	Signal  syscall.Signal
+	WorkDir string
+}
+
+// Shell is a long-living bash session running in a pseudo-terminal.
+// All commands are executed in the same session, so that environment variables,
+// aliases, functions and shell options survive between the commands.
+//
+// The end of every command is detected by a marker, which is printed by
+// PROMPT_COMMAND right before bash shows the next prompt.
+// The marker contains the exit code of the command and the current working directory.
+// The continuation prompt (PS2) is a marker as well, so that incomplete commands are detected.
+// The exported variables and aliases are saved to the state file on every prompt as well,
+// so that they could be restored by another session (e.g. of a background job).
+type Shell struct {
+	cfg     ShellConfig
+	runner  *exec.Cmd
+	tty     *os.File
+	size    *pty.Winsize
+	parser  *markerParser
+	output  io.Writer
+	done    chan Result
+	exited  chan struct{}
+	mu      *sync.Mutex
+	execMu  *sync.Mutex
+	started bool
+	// number of the continuation prompts, which are expected for the lines of the running command
+	continuations int
+	// the running command has been cancelled, since it's incomplete
+	incomplete bool
+	// the file, which keeps the state of the session
+	stateFile string
+}
+
+const (
+	markerPrefix = "\033]777;gooster;"
+	markerSuffix = '\007'
+	// moreMarker is printed as the continuation prompt
+	moreMarker = "more"
+)
+
+// shellInit is sent to a new shell session before any other command.
+// It disables echo (the commands are printed by gooster itself),
+// hides the prompts, and installs the marker printer.
+var shellInit = strings.Join([]string{
+	`stty -echo 2>/dev/null`,
+	`set +o history`,
+	`PS1=''`,
+	`PS2='\033]777;gooster;` + moreMarker + `\007'`,
+	`__gooster_done() { local rc=$?; stty -echo 2>/dev/null; { export -p; alias -p; } > "$__gooster_state" 2>/dev/null; printf '` + `\033]777;gooster;%s;%s\007` + `' "$rc" "$PWD"; }`,
+	`PROMPT_COMMAND=__gooster_done`,
+}, "; ")
+
+func NewShell(cfg ShellConfig) *Shell {
+	if cfg.Bin == "" {
+		cfg.Bin = "bash"
+	}
+	if cfg.Output == nil {
+		cfg.Output = ioutil.Discard
+	}
+
+	return &Shell{cfg: cfg, parser: &markerParser{}, mu: &sync.Mutex{}, execMu: &sync.Mutex{}}
+}
+
+// Start runs a new shell process and waits until it's ready to accept commands.
+func (s *Shell) Start() (err error) {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return nil
+	}
+
+	if s.stateFile == "" {
+		if s.stateFile, err = s.createStateFile(); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	s.runner = exec.Command(s.cfg.Bin, append([]string{"--noediting"}, s.cfg.Args...)...)
+	s.runner.Env = append(os.Environ(), "TERM=xterm-256color")
+
+	if s.tty, err = pty.StartWithSize(s.runner, s.size); err != nil {
+		s.mu.Unlock()
+		return errors.WithMessage(err, "start shell in pty")
+	}
+	s.started = true
+	// everything printed before the first marker (motd, rc-files output, etc) is ignored
+	s.output = ioutil.Discard
+	s.done = make(chan Result, 1)
+	s.exited = make(chan struct{})
+	s.mu.Unlock()
+
+	go s.read()
+	go s.wait()
+
+	if _, err = s.tty.Write([]byte(s.init() + "\n")); err != nil {
+		return errors.WithMessage(err, "init shell")
+	}
+	_, err = s.await()
+	return err
+}
+
+// init returns the commands, which prepare the new session.
+func (s *Shell) init() string {
+	cmds := []string{shellInit, "__gooster_state=" + shellQuote(s.stateFile)}
+	if len(s.cfg.State) > 0 {
+		cmds = append(cmds, `source "$__gooster_state" 2>/dev/null`)
+	}
+	// the restored PWD is not valid in this session, and the dir could have been removed meanwhile
+	if s.cfg.Dir != "" {
+		cmds = append(cmds, "{ cd -- "+shellQuote(s.cfg.Dir)+" || cd -P .; } 2>/dev/null")
+	} else if len(s.cfg.State) > 0 {
+		cmds = append(cmds, "cd -P . 2>/dev/null")
+	}
+	return strings.Join(cmds, "; ")
+}
+
+// createStateFile creates the state file, which is readable by the user only, since it keeps the variables.
+func (s *Shell) createStateFile() (string, error) {
+	file, err := ioutil.TempFile("", "gooster-shell-*.sh")
+	if err != nil {
+		return "", errors.WithMessage(err, "create shell state file")
+	}
+	_, err = file.Write(s.cfg.State)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", errors.WithMessage(err, "write shell state file")
+	}
+	return file.Name(), nil
+}
+
+// State returns the exported variables and aliases of the session, as they were after the latest command.
+func (s *Shell) State() ([]byte, error) {
+	s.mu.Lock()
+	path := s.stateFile
+	s.mu.Unlock()
+	if path == "" {
+		return s.cfg.State, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "read shell state file")
+	}
+	return data, nil
+}
+
+// Exec runs the command in the shell and blocks until it's finished.
+// Concurrent calls are executed one after another.
+func (s *Shell) Exec(cmd string, output io.Writer) (Result, error) {
+	s.execMu.Lock()
+	defer s.execMu.Unlock()
+
+	if err := s.Start(); err != nil {
+		return Result{}, err
+	}
+
+	if strings.Contains(cmd, "\n") {
+		// the lines are grouped, so that the shell reports the end of the command after the last line
+		cmd = "{ " + cmd + "\n}"
+	}
+
+	s.mu.Lock()
+	s.output = output
+	s.done = make(chan Result, 1)
+	// every next line of the command is read after the continuation prompt
+	s.continuations = strings.Count(cmd, "\n")
+	s.incomplete = false
+	s.mu.Unlock()
+
+	// echo is enabled only while the command is running,
+	// so that the user input, requested by the command, is visible
+	if _, err := s.tty.Write([]byte("stty echo 2>/dev/null; " + cmd + "\n")); err != nil {
+		return Result{}, errors.WithMessage(err, "send command to shell")
+	}
+	res, err := s.await()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil && s.incomplete {
+		err = errors.New("incomplete command (e.g. a non closed quote or bracket)")
+	}
+	return res, err
+}
+
+// Write sends user input to the currently running command.
+func (s *Shell) Write(p []byte) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return 0, errors.New("shell is not started")
+	}
+	return s.tty.Write(p)
+}
+
+const ctrlC byte = 3
+
+// Interrupt sends SIGINT to the currently running command.
+func (s *Shell) Interrupt() error {
+	_, err := s.Write([]byte{ctrlC})
+	return err
+}
+
+// Kill sends SIGTERM to the currently running command (the foreground process group of the terminal).
+func (s *Shell) Kill() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return errors.New("shell is not started")
+	}
+	pgrp, err := unix.IoctlGetInt(int(s.tty.Fd()), unix.TIOCGPGRP)
+	if err != nil {
+		return errors.WithMessage(err, "get foreground process group")
+	}
+	if pgrp == s.runner.Process.Pid {
+		// the shell itself is in the foreground, so there is no running command
+		return nil
+	}
+	return errors.WithMessage(syscall.Kill(-pgrp, syscall.SIGTERM), "kill command")
+}
+
+// SetSize defines the window size of the terminal the shell is running in.
+// It can be called before or after the shell has been started.
+func (s *Shell) SetSize(width, height int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.size = &pty.Winsize{Cols: uint16(width), Rows: uint16(height)}
+	if s.started {
+		_ = pty.Setsize(s.tty, s.size)
+	}
+}
+
+func (s *Shell) Close() error {
+	s.mu.Lock()
+	started, exited, stateFile := s.started, s.exited, s.stateFile
+	s.stateFile = ""
+	s.mu.Unlock()
+	if stateFile != "" {
+		defer func() { _ = os.Remove(stateFile) }()
+	}
+	if !started {
+		return nil
+	}
+
+	if err := s.runner.Process.Kill(); err != nil {
+		return errors.WithMessage(err, "kill shell")
+	}
+	<-exited
+	return nil
+}
+
+func (s *Shell) await() (Result, error) {
+	s.mu.Lock()
+	done, exited := s.done, s.exited
+	s.mu.Unlock()
+
+	select {
+	case res := <-done:
+		return res, nil
+	case <-exited:
+		res := Result{ExitCode: s.runner.ProcessState.ExitCode()}
+		if status, ok := s.runner.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			res.Signal = status.Signal()
+		}
+		return res, errors.New("shell has exited")
+	}
+}
+
+func (s *Shell) read() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := s.tty.Read(buf)
+		if n > 0 {
+			s.handleOutput(buf[:n])
+		}
+		// reading fails (with EIO on linux) as soon as the shell has closed the terminal
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Shell) handleOutput(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out, markers := s.parser.parse(data)
+	for i := range out {
+		if len(out[i]) > 0 {
+			_, _ = s.output.Write(out[i])
+		}
+		if i < len(markers) && string(markers[i]) == moreMarker {
+			s.continueCommand()
+		} else if i < len(markers) {
+			s.finishCommand(markers[i])
+		}
+	}
+}
+
+// continueCommand handles the continuation prompt. If all lines of the command have been read,
+// then the shell waits for more input, which would never come, so the command is cancelled.
+func (s *Shell) continueCommand() {
+	if s.continuations > 0 {
+		s.continuations--
+		return
+	}
+	if s.done != nil && !s.incomplete {
+		s.incomplete = true
+		_, _ = s.tty.Write([]byte{ctrlC})
+	}
+}
+
+func (s *Shell) finishCommand(marker []byte) {
+	res := parseMarker(marker)
+	s.output = s.cfg.Output
+	if s.done != nil {
+		s.done <- res
+		s.done = nil
+	}
+}
+
+func (s *Shell) wait() {
+	_ = s.runner.Wait()
+	_ = s.tty.Close()
+
+	s.mu.Lock()
+	s.started = false
+	s.mu.Unlock()
+	close(s.exited)
+}
+
+func parseMarker(marker []byte) (res Result) {
+	parts := strings.SplitN(string(marker), ";", 2)
+	res.ExitCode, _ = strconv.Atoi(parts[0])
+	if len(parts) > 1 {
+		res.WorkDir = parts[1]
+	}
+	return res
+}
+
+// markerParser extracts markers from the shell output.
+// A marker can be split between several chunks of the output,
+// so the parser keeps the incomplete tail till the next chunk arrives.
+type markerParser struct {
+	pending []byte
+}
+
+// parse returns the output chunks with the markers between them:
+// out[0], markers[0], out[1], markers[1], ..., out[n].
+func (p *markerParser) parse(data []byte) (out [][]byte, markers [][]byte) {
+	if len(p.pending) > 0 {
+		data = append(p.pending, data...)
+		p.pending = nil
+	}
+
+	for {
+		start := bytes.Index(data, []byte(markerPrefix))
+		if start < 0 {
+			keep := partialPrefixLen(data, []byte(markerPrefix))
+			p.pending = append(p.pending, data[len(data)-keep:]...)
+			return append(out, data[:len(data)-keep]), markers
+		}
+
+		end := bytes.IndexByte(data[start:], markerSuffix)
+		if end < 0 {
+			p.pending = append(p.pending, data[start:]...)
+			return append(out, data[:start]), markers
+		}
+
+		out = append(out, data[:start])
+		markers = append(markers, data[start+len(markerPrefix):start+end])
+		data = data[start+end+1:]
+	}
+}
+
+// partialPrefixLen returns length of the longest tail of the data,
+// which could be a beginning of the prefix.
+func partialPrefixLen(data, prefix []byte) int {
+	max := len(prefix) - 1
+	if len(data) < max {
+		max = len(data)
+	}
+	for n := max; n > 0; n-- {
+		if bytes.HasPrefix(prefix, data[len(data)-n:]) {
+			return n
+		}
+	}
+	return 0
+}
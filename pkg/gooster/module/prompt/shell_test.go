@@ -0,0 +1,194 @@
+package prompt
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShell(t *testing.T) {
+	assert := require.New(t)
+
+	newShell := func() *Shell {
+		return NewShell(ShellConfig{Args: []string{"--norc", "--noprofile"}})
+	}
+
+	t.Run("should execute commands and return their output", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+
+		out := bytes.NewBuffer(nil)
+		res, err := sh.Exec(`echo "foo"`, &crlfWriter{target: out})
+		assert.NoError(err)
+		assert.Equal(0, res.ExitCode)
+		assert.Equal("foo\n", out.String())
+	})
+
+	t.Run("should return exit code of the command", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+
+		res, err := sh.Exec(`(exit 3)`, ioutil.Discard)
+		assert.NoError(err)
+		assert.Equal(3, res.ExitCode)
+	})
+
+	t.Run("should not report exit codes of the commands as signals", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+
+		res, err := sh.Exec(`(exit 130)`, ioutil.Discard)
+		assert.NoError(err)
+		assert.Equal(130, res.ExitCode)
+		assert.Equal(syscall.Signal(0), res.Signal)
+	})
+
+	t.Run("should report the signal, which has terminated the shell", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+
+		res, err := sh.Exec(`kill -KILL $$`, ioutil.Discard)
+		assert.Error(err)
+		assert.Equal(syscall.SIGKILL, res.Signal)
+	})
+
+	t.Run("should interrupt the running command", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+		assert.NoError(sh.Start())
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			assert.NoError(sh.Interrupt())
+		}()
+		res, err := sh.Exec(`sleep 5`, ioutil.Discard)
+		assert.NoError(err)
+		assert.Equal(130, res.ExitCode)
+	})
+
+	t.Run("should execute all lines of a multi-line command", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+
+		out := bytes.NewBuffer(nil)
+		res, err := sh.Exec("echo foo\nfor i in 1 2\ndo\n  echo $i\ndone\n(exit 2)", &crlfWriter{target: out})
+		assert.NoError(err)
+		assert.Equal(2, res.ExitCode)
+		assert.Equal("foo\n1\n2\n", out.String())
+	})
+
+	t.Run("should cancel incomplete commands", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+
+		for _, cmd := range []string{`echo "foo`, "(echo foo", "cat <<EOF\nfoo"} {
+			_, err := sh.Exec(cmd, ioutil.Discard)
+			assert.Error(err, cmd)
+		}
+
+		out := bytes.NewBuffer(nil)
+		res, err := sh.Exec("cat <<EOF\nfoo\nEOF", &crlfWriter{target: out})
+		assert.NoError(err)
+		assert.Equal(0, res.ExitCode)
+		assert.Equal("foo\n", out.String())
+	})
+
+	t.Run("should keep the shell state between commands", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+
+		_, err := sh.Exec(`export FOO=bar; greet() { echo "hello $1"; }`, ioutil.Discard)
+		assert.NoError(err)
+
+		out := bytes.NewBuffer(nil)
+		_, err = sh.Exec(`greet "$FOO"`, &crlfWriter{target: out})
+		assert.NoError(err)
+		assert.Equal("hello bar\n", out.String())
+	})
+
+	t.Run("should report the current work dir", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+
+		tmp := os.TempDir()
+		res, err := sh.Exec("pushd "+shellQuote(tmp)+" > /dev/null", ioutil.Discard)
+		assert.NoError(err)
+		assert.Equal(tmp, res.WorkDir)
+	})
+
+	t.Run("should start in the work dir with the state of another session", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+		_, err := sh.Exec(`export FOO="b'ar"; alias greet='echo hello'`, ioutil.Discard)
+		assert.NoError(err)
+		state, err := sh.State()
+		assert.NoError(err)
+
+		tmp := os.TempDir()
+		other := NewShell(ShellConfig{Args: []string{"--norc", "--noprofile"}, Dir: tmp, State: state})
+		defer func() { assert.NoError(other.Close()) }()
+
+		out := bytes.NewBuffer(nil)
+		res, err := other.Exec(`greet "$FOO"`, &crlfWriter{target: out})
+		assert.NoError(err)
+		assert.Equal("hello b'ar\n", out.String())
+		assert.Equal(tmp, res.WorkDir)
+	})
+
+	t.Run("should kill the running command", func(t *testing.T) {
+		sh := newShell()
+		defer func() { assert.NoError(sh.Close()) }()
+		assert.NoError(sh.Start())
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			assert.NoError(sh.Kill())
+		}()
+		res, err := sh.Exec(`trap '' INT; sleep 5`, ioutil.Discard)
+		assert.NoError(err)
+		assert.Equal(143, res.ExitCode)
+	})
+}
+
+func TestMarkerParser(t *testing.T) {
+	assert := require.New(t)
+	str := func(chunks [][]byte) (list []string) {
+		for _, chunk := range chunks {
+			list = append(list, string(chunk))
+		}
+		return list
+	}
+
+	t.Run("should split output by markers", func(t *testing.T) {
+		p := &markerParser{}
+		out, markers := p.parse([]byte("foo" + markerPrefix + "0;/foo\007bar"))
+		assert.Equal([]string{"foo", "bar"}, str(out))
+		assert.Equal([]string{"0;/foo"}, str(markers))
+	})
+
+	t.Run("should detect markers split between chunks", func(t *testing.T) {
+		p := &markerParser{}
+		out, markers := p.parse([]byte("foo\033]77"))
+		assert.Equal([]string{"foo"}, str(out))
+		assert.Empty(markers)
+
+		out, markers = p.parse([]byte("7;gooster;1;/b"))
+		assert.Equal([]string{""}, str(out))
+		assert.Empty(markers)
+
+		out, markers = p.parse([]byte("ar\007baz"))
+		assert.Equal([]string{"", "baz"}, str(out))
+		assert.Equal([]string{"1;/bar"}, str(markers))
+	})
+
+	t.Run("should not hold escape sequences which are not markers", func(t *testing.T) {
+		p := &markerParser{}
+		out, markers := p.parse([]byte("foo\033[0m"))
+		assert.Equal([]string{"foo\033[0m"}, str(out))
+		assert.Empty(markers)
+	})
+}
@@ -5,3 +5,7 @@ type EventShowInStatus struct {
 	Value string // string value, may include string formatting
 	Align int    // tview.Align* constants
 }
+
+func (e EventShowInStatus) NeedsDraw() bool {
+	return true
+}
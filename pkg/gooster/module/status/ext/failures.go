@@ -0,0 +1,71 @@
+package ext
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/config"
+	"github.com/jumale/gooster/pkg/events"
+	"github.com/jumale/gooster/pkg/gooster"
+	"github.com/jumale/gooster/pkg/gooster/module/status"
+	"github.com/rivo/tview"
+)
+
+type FailuresConfig struct {
+	Col    int                  `json:"col"`
+	Align  int                  `json:"align"` // tview.Align* constants
+	Colors FailuresColorsConfig `json:"colors"`
+}
+
+type FailuresColorsConfig struct {
+	Warning config.Color `json:"warning"`
+}
+
+// Failures shows a warning, when an event handler has panicked.
+type Failures struct {
+	gooster.Context
+	cfg      FailuresConfig
+	failures int
+	disabled int
+}
+
+func NewFailures() gooster.Extension {
+	return &Failures{cfg: FailuresConfig{
+		Col:   3,
+		Align: tview.AlignRight,
+		Colors: FailuresColorsConfig{
+			Warning: config.Color(tcell.ColorOrange),
+		},
+	}}
+}
+
+func (ext *Failures) Name() string {
+	return "failures"
+}
+
+func (ext *Failures) Init(_ gooster.Module, ctx gooster.Context) error {
+	ext.Context = ctx
+	if err := ctx.LoadConfig(&ext.cfg); err != nil {
+		return err
+	}
+
+	ctx.Events().Subscribe(events.OnWithPrio(events.AfterAllOtherChanges, ext.handleEventSubscriberFailed))
+	return nil
+}
+
+func (ext *Failures) handleEventSubscriberFailed(event events.EventSubscriberFailed) {
+	ext.failures++
+	if event.Disabled {
+		ext.disabled++
+	}
+
+	value := fmt.Sprintf("⚠ %d handler failures", ext.failures)
+	if ext.disabled > 0 {
+		value += fmt.Sprintf(", %d disabled", ext.disabled)
+	}
+
+	ext.Events().Dispatch(status.EventShowInStatus{
+		Value: fmt.Sprintf("[#%06x]%s[-]", ext.cfg.Colors.Warning.Origin().Hex(), value),
+		Col:   ext.cfg.Col,
+		Align: ext.cfg.Align,
+	})
+}
@@ -0,0 +1,86 @@
+package ext
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/config"
+	"github.com/jumale/gooster/pkg/events"
+	"github.com/jumale/gooster/pkg/gooster"
+	"github.com/jumale/gooster/pkg/gooster/module/prompt"
+	"github.com/jumale/gooster/pkg/gooster/module/status"
+	"github.com/rivo/tview"
+	"time"
+)
+
+type LastCommandConfig struct {
+	Col    int                     `json:"col"`
+	Align  int                     `json:"align"` // tview.Align* constants
+	Colors LastCommandColorsConfig `json:"colors"`
+}
+
+type LastCommandColorsConfig struct {
+	Success  config.Color `json:"success"`
+	Failure  config.Color `json:"failure"`
+	Duration config.Color `json:"duration"`
+}
+
+// LastCommand shows exit code and duration of the latest finished command.
+type LastCommand struct {
+	gooster.Context
+	cfg LastCommandConfig
+}
+
+func NewLastCommand() gooster.Extension {
+	return &LastCommand{cfg: LastCommandConfig{
+		Col:   1,
+		Align: tview.AlignRight,
+		Colors: LastCommandColorsConfig{
+			Success:  config.Color(tcell.ColorLightGreen),
+			Failure:  config.Color(tcell.ColorRed),
+			Duration: config.Color(tcell.ColorLightGray),
+		},
+	}}
+}
+
+func (ext *LastCommand) Name() string {
+	return "last_command"
+}
+
+func (ext *LastCommand) Init(_ gooster.Module, ctx gooster.Context) error {
+	ext.Context = ctx
+	if err := ctx.LoadConfig(&ext.cfg); err != nil {
+		return err
+	}
+
+	ctx.Events().Subscribe(events.OnWithPrio(events.AfterAllOtherChanges, ext.handleEventCommandFinished))
+	return nil
+}
+
+func (ext *LastCommand) handleEventCommandFinished(event prompt.EventCommandFinished) {
+	color := ext.cfg.Colors.Success.Origin()
+	if !event.Success() {
+		color = ext.cfg.Colors.Failure.Origin()
+	}
+
+	code := fmt.Sprintf("%d", event.ExitCode)
+	if event.Signal != 0 {
+		code = fmt.Sprintf("%d (%s)", event.ExitCode, event.Signal)
+	}
+
+	ext.Events().Dispatch(status.EventShowInStatus{
+		Value: fmt.Sprintf(
+			"[#%06x]%s[-] [#%06x]%s[-]",
+			color.Hex(), code,
+			ext.cfg.Colors.Duration.Origin().Hex(), formatDuration(event.Duration()),
+		),
+		Col:   ext.cfg.Col,
+		Align: ext.cfg.Align,
+	})
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,77 @@
+package ext
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/config"
+	"github.com/jumale/gooster/pkg/gooster/module/prompt"
+	"github.com/jumale/gooster/pkg/gooster/module/status"
+	tools "github.com/jumale/gooster/pkg/gooster/test_tools"
+	"github.com/rivo/tview"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestLastCommand(t *testing.T) {
+	cfg := LastCommandConfig{
+		Col:   1,
+		Align: tview.AlignRight,
+		Colors: LastCommandColorsConfig{
+			Success:  config.Color(tcell.ColorGreen),
+			Failure:  config.Color(tcell.ColorRed),
+			Duration: config.Color(tcell.ColorGray),
+		},
+	}
+	startedAt := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("should show exit code and duration of the successful command", func(t *testing.T) {
+		ext := tools.NewExtensionTester(t, NewLastCommand(), nil, cfg)
+		ext.AssertInited()
+
+		ext.SendEvent(prompt.EventCommandFinished{
+			Cmd:        "ls",
+			StartedAt:  startedAt,
+			FinishedAt: startedAt.Add(1500 * time.Millisecond),
+		})
+		ext.AssertFinalEvent(status.EventShowInStatus{
+			Value: "[#008000]0[-] [#808080]1.5s[-]",
+			Col:   1,
+			Align: tview.AlignRight,
+		})
+	})
+
+	t.Run("should show the failed command in the failure color", func(t *testing.T) {
+		ext := tools.NewExtensionTester(t, NewLastCommand(), nil, cfg)
+		ext.AssertInited()
+
+		ext.SendEvent(prompt.EventCommandFinished{
+			Cmd:        "make",
+			ExitCode:   130,
+			StartedAt:  startedAt,
+			FinishedAt: startedAt.Add(90 * time.Second),
+		})
+		ext.AssertFinalEvent(status.EventShowInStatus{
+			Value: "[#ff0000]130[-] [#808080]1m30s[-]",
+			Col:   1,
+			Align: tview.AlignRight,
+		})
+	})
+
+	t.Run("should show the signal, which has terminated the command", func(t *testing.T) {
+		ext := tools.NewExtensionTester(t, NewLastCommand(), nil, cfg)
+		ext.AssertInited()
+
+		ext.SendEvent(prompt.EventCommandFinished{
+			Cmd:        "sleep 10",
+			ExitCode:   -1,
+			Signal:     syscall.SIGKILL,
+			StartedAt:  startedAt,
+			FinishedAt: startedAt.Add(time.Second),
+		})
+		ext.AssertFinalEvent(status.EventShowInStatus{
+			Value: "[#ff0000]-1 (killed)[-] [#808080]1s[-]",
+			Col:   1,
+			Align: tview.AlignRight,
+		})
+	})
+}
@@ -0,0 +1,71 @@
+package ext
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/config"
+	"github.com/jumale/gooster/pkg/events"
+	"github.com/jumale/gooster/pkg/gooster"
+	"github.com/jumale/gooster/pkg/gooster/module/output"
+	"github.com/jumale/gooster/pkg/gooster/module/status"
+	"github.com/rivo/tview"
+)
+
+type SearchConfig struct {
+	Col    int                `json:"col"`
+	Align  int                `json:"align"` // tview.Align* constants
+	Colors SearchColorsConfig `json:"colors"`
+}
+
+type SearchColorsConfig struct {
+	Found    config.Color `json:"found"`
+	NotFound config.Color `json:"not_found"`
+}
+
+// Search shows the match counter of the output search.
+type Search struct {
+	gooster.Context
+	cfg SearchConfig
+}
+
+func NewSearch() gooster.Extension {
+	return &Search{cfg: SearchConfig{
+		Col:   2,
+		Align: tview.AlignCenter,
+		Colors: SearchColorsConfig{
+			Found:    config.Color(tcell.ColorLightGreen),
+			NotFound: config.Color(tcell.ColorRed),
+		},
+	}}
+}
+
+func (ext *Search) Name() string {
+	return "search"
+}
+
+func (ext *Search) Init(_ gooster.Module, ctx gooster.Context) error {
+	ext.Context = ctx
+	if err := ctx.LoadConfig(&ext.cfg); err != nil {
+		return err
+	}
+
+	ctx.Events().Subscribe(events.OnWithPrio(events.AfterAllOtherChanges, ext.handleEventSearchUpdated))
+	return nil
+}
+
+func (ext *Search) handleEventSearchUpdated(event output.EventSearchUpdated) {
+	value := ""
+	if event.Active && event.Query != "" {
+		color := ext.cfg.Colors.Found.Origin()
+		if event.Total == 0 {
+			color = ext.cfg.Colors.NotFound.Origin()
+		}
+		value = fmt.Sprintf("[#%06x]%d/%d[-] %s", color.Hex(), event.Current, event.Total, tview.Escape(event.Query))
+	}
+
+	ext.Events().Dispatch(status.EventShowInStatus{
+		Value: value,
+		Col:   ext.cfg.Col,
+		Align: ext.cfg.Align,
+	})
+}
@@ -50,13 +50,7 @@ func (ext *WorkDir) Init(_ gooster.Module, ctx gooster.Context) error {
 		return err
 	}
 
-	ctx.Events().Subscribe(events.HandleWithPrio(events.AfterAllOtherChanges, func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case workdir.EventChangeDir:
-			ext.handleEventChangeDir(event)
-		}
-		return e
-	}))
+	ctx.Events().Subscribe(events.OnWithPrio(events.AfterAllOtherChanges, ext.handleEventChangeDir))
 	return nil
 }
 
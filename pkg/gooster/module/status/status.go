@@ -49,15 +49,11 @@ func (m *Module) Init(ctx gooster.Context) error {
 	m.view.SetBorders(false)
 	m.view.SetBackgroundColor(m.cfg.Colors.Bg.Origin())
 
-	m.Events().Subscribe(events.HandleWithPrio(events.AfterAllOtherChanges, func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case EventShowInStatus:
-			cell := tview.NewTableCell(event.Value)
-			cell.SetExpansion(2)
-			cell.SetAlign(event.Align)
-			m.view.SetCell(0, event.Col, cell)
-		}
-		return e
+	m.Events().Subscribe(events.OnWithPrio(events.AfterAllOtherChanges, func(event EventShowInStatus) {
+		cell := tview.NewTableCell(event.Value)
+		cell.SetExpansion(2)
+		cell.SetAlign(event.Align)
+		m.view.SetCell(0, event.Col, cell)
 	}))
 	return nil
 }
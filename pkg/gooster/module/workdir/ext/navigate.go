@@ -40,14 +40,10 @@ func (ext *TypingSearch) Init(m gooster.Module, ctx gooster.Context) error {
 		return err
 	}
 
-	ext.Events().Subscribe(events.HandleWithPrio(-100, func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case workdir.EventSetChildren:
-			ext.Lock()
-			ext.children = event.Children
-			ext.Unlock()
-		}
-		return e
+	ext.Events().Subscribe(events.OnWithPrio(-100, func(event workdir.EventSetChildren) {
+		ext.Lock()
+		ext.children = event.Children
+		ext.Unlock()
 	}))
 
 	prev := m.View().GetBox().GetInputCapture()
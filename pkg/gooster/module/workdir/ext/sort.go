@@ -33,13 +33,9 @@ func (ext *SortTree) Init(_ gooster.Module, ctx gooster.Context) error {
 		return err
 	}
 
-	ctx.Events().Subscribe(events.HandleWithPrio(100, func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case workdir.EventSetChildren:
-			event.Children = ext.sort(event.Children)
-			return event
-		}
-		return e
+	ctx.Events().Subscribe(events.OnWithPrio(100, func(event workdir.EventSetChildren) events.IEvent {
+		event.Children = ext.sort(event.Children)
+		return event
 	}))
 	return nil
 }
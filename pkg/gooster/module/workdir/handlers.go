@@ -1,6 +1,7 @@
 package workdir
 
 import (
+	"context"
 	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/jumale/gooster/pkg/dialog"
@@ -18,11 +19,22 @@ func (m *Module) handleEventRefresh() {
 
 func (m *Module) handleEventChangeDir(event EventChangeDir) {
 	m.workDir = event.Path
-	if err := m.fs.Chdir(m.workDir); err != nil {
-		m.Log().Error(errors.WithMessage(err, "change work dir"))
-		return
+	if m.active {
+		if err := m.fs.Chdir(m.workDir); err != nil {
+			m.Log().Error(errors.WithMessage(err, "change work dir"))
+			return
+		}
 	}
 	m.handleEventRefresh()
+	m.saveState()
+}
+
+// handleEventTabActivated restores the process work dir, which could be changed by another tab.
+func (m *Module) handleEventTabActivated() {
+	m.active = true
+	if err := m.fs.Chdir(m.workDir); err != nil {
+		m.Log().Error(errors.WithMessage(err, "restore work dir"))
+	}
 }
 
 func (m *Module) handleEventSetChildren(event EventSetChildren) {
@@ -31,6 +43,45 @@ func (m *Module) handleEventSetChildren(event EventSetChildren) {
 		list = append(list, child.TreeNode)
 	}
 	event.Target.SetChildren(list)
+	m.expandPending()
+	m.saveState()
+}
+
+func (m *Module) handleEventRestoreState(event gooster.EventRestoreState) {
+	var st state
+	if ok, err := event.Modules.Load(m.Name(), &st); err != nil || !ok {
+		m.Log().Check(err)
+		return
+	}
+	// the nodes are loaded asynchronously, so they are expanded when they appear in the tree
+	m.pending = st.Expanded
+	m.Events().Dispatch(EventChangeDir{Path: st.WorkDir})
+}
+
+// handleQuerySessionState responds with the copy of the state, since the tree is changed by the UI goroutine.
+func (m *Module) handleQuerySessionState(_ context.Context, _ gooster.QuerySessionState) (gooster.ModuleStates, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return gooster.NewModuleState(m.Name(), m.saved)
+}
+
+// expandPending expands the restored nodes, which have appeared in the tree.
+func (m *Module) expandPending() {
+	for {
+		var node *dirtree.Node
+		for i, path := range m.pending {
+			if node = m.tree.Find(path); node != nil {
+				m.pending = append(m.pending[:i:i], m.pending[i+1:]...)
+				break
+			}
+		}
+		if node == nil {
+			return
+		}
+		// the children are set by an event, which expands the nested nodes in turn
+		m.tree.ExpandNode(node.TreeNode)
+	}
 }
 
 func (m *Module) handleEventActivateNode(event EventActivateNode) {
@@ -9,6 +9,7 @@ import (
 	"github.com/jumale/gooster/pkg/gooster"
 	"github.com/rivo/tview"
 	"os"
+	"sync"
 )
 
 type Module struct {
@@ -18,6 +19,19 @@ type Module struct {
 	tree    *dirtree.DirTree
 	view    *tview.TreeView
 	fs      filesys.FileSys
+	// the restored nodes, which are expanded as soon as they appear in the tree
+	pending []string
+	// the process work dir is shared by the tabs, so that it's changed by the active tab only
+	active bool
+	// the copy of the state for the session requests, which are handled outside of the UI goroutine
+	saved state
+	mu    *sync.Mutex
+}
+
+// state is the part of the module, which is kept in the session.
+type state struct {
+	WorkDir  string   `json:"work_dir"`
+	Expanded []string `json:"expanded,omitempty"`
 }
 
 func NewModule() gooster.Module {
@@ -27,6 +41,7 @@ func NewModule() gooster.Module {
 func newModule(fs filesys.FileSys) *Module {
 	return &Module{
 		fs: fs,
+		mu: &sync.Mutex{},
 		cfg: Config{
 			InitDir: getWd(),
 			Colors: ColorsConfig{
@@ -77,7 +92,10 @@ func (m *Module) Init(ctx gooster.Context) error {
 	m.view.SetBorder(false)
 	m.view.SetBackgroundColor(m.cfg.Colors.Bg.Origin())
 	m.view.SetGraphicsColor(m.cfg.Colors.Graphics.Origin())
-	m.view.SetSelectedFunc(m.tree.ExpandNode)
+	m.view.SetSelectedFunc(func(node *tview.TreeNode) {
+		m.tree.ExpandNode(node)
+		m.saveState()
+	})
 
 	m.view.SetKeyBinding(tview.TreeMoveUp, rune(tcell.KeyUp))
 	m.view.SetKeyBinding(tview.TreeMoveDown, rune(tcell.KeyDown))
@@ -87,29 +105,21 @@ func (m *Module) Init(ctx gooster.Context) error {
 	m.view.SetKeyBinding(tview.TreeMoveEnd, rune(tcell.KeyEnd))
 	m.view.SetKeyBinding(tview.TreeSelectNode, rune(tcell.KeyLeft), rune(tcell.KeyRight))
 
-	m.Events().Subscribe(events.HandleFunc(func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case EventRefresh:
-			m.handleEventRefresh()
-		case EventChangeDir:
-			m.handleEventChangeDir(event)
-		case EventSetChildren:
-			m.handleEventSetChildren(event)
-		case EventActivateNode:
-			m.handleEventActivateNode(event)
-		case EventCreateFile:
-			m.handleEventCreateFile(event)
-		case EventCreateDir:
-			m.handleEventCreateDir(event)
-		case EventViewFile:
-			m.handleEventViewFile(event)
-		case EventDelete:
-			m.handleEventDelete(event)
-		case EventOpen:
-			m.handleEventOpen(event)
-		}
-		return e
-	}))
+	m.Events().Subscribe(
+		events.On(func(EventRefresh) { m.handleEventRefresh() }),
+		events.On(m.handleEventChangeDir),
+		events.On(func(gooster.EventTabActivated) { m.handleEventTabActivated() }),
+		events.On(func(gooster.EventTabDeactivated) { m.active = false }),
+		events.On(m.handleEventSetChildren),
+		events.On(m.handleEventActivateNode),
+		events.On(m.handleEventCreateFile),
+		events.On(m.handleEventCreateDir),
+		events.On(m.handleEventViewFile),
+		events.On(m.handleEventDelete),
+		events.On(m.handleEventOpen),
+		events.On(m.handleEventRestoreState),
+		events.Respond(m.handleQuerySessionState),
+	)
 
 	gooster.HandleKeyEvents(m.view, gooster.KeyEventHandlers{
 		m.cfg.Keys.NewFile: m.handleKeyNewFile,
@@ -123,6 +133,14 @@ func (m *Module) Init(ctx gooster.Context) error {
 	return nil
 }
 
+// saveState updates the copy of the state, it must be called whenever the work dir or the tree is changed.
+func (m *Module) saveState() {
+	st := state{WorkDir: m.workDir, Expanded: m.tree.ExpandedPaths()}
+	m.mu.Lock()
+	m.saved = st
+	m.mu.Unlock()
+}
+
 func (m *Module) currentNode() *dirtree.Node {
 	return m.view.GetCurrentNode().GetReference().(*dirtree.Node)
 }
@@ -0,0 +1,333 @@
+package gooster
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// defaultPaneSize is the initial proportion of a pane within its split.
+const defaultPaneSize = 10
+
+type SplitDirection int
+
+const (
+	// SplitRight places the new pane to the right of the current one.
+	SplitRight SplitDirection = tview.FlexColumn
+	// SplitDown places the new pane below the current one.
+	SplitDown SplitDirection = tview.FlexRow
+)
+
+// pane is a set of pane modules (e.g. output and prompt) with their own event scope.
+type pane struct {
+	id   string
+	view *tview.Grid
+	node *paneNode
+}
+
+// paneNode is either a pane or a split of the area between its children.
+type paneNode struct {
+	pane      *pane
+	direction SplitDirection
+	children  []*paneNode
+	parent    *paneNode
+	// proportion of the node within the parent split
+	size int
+}
+
+// leaves returns all panes of the node in order of their appearance.
+func (n *paneNode) leaves() []*pane {
+	if n.pane != nil {
+		return []*pane{n.pane}
+	}
+	var panes []*pane
+	for _, child := range n.children {
+		panes = append(panes, child.leaves()...)
+	}
+	return panes
+}
+
+// contains tells whether the pane is within the node.
+func (n *paneNode) contains(p *pane) bool {
+	for node := p.node; node != nil; node = node.parent {
+		if node == n {
+			return true
+		}
+	}
+	return false
+}
+
+// build creates the view of the node. The focus is given to the branch, which contains the focused pane.
+func (n *paneNode) build(focused *pane, cfg PanesConfig) tview.Primitive {
+	if n.pane != nil {
+		return n.pane.view
+	}
+
+	flex := tview.NewFlex().SetDirection(int(n.direction))
+	for i, child := range n.children {
+		if i > 0 {
+			separator := tview.NewBox()
+			separator.SetBackgroundColor(cfg.Separator.Origin())
+			flex.AddItem(separator, 1, 0, false)
+		}
+		flex.AddItem(child.build(focused, cfg), 0, child.size, focused != nil && child.contains(focused))
+	}
+	return flex
+}
+
+// replace puts the new node to the place of the old one.
+func (n *paneNode) replace(old, new *paneNode) {
+	for i, child := range n.children {
+		if child == old {
+			n.children[i] = new
+			new.parent = n
+			return
+		}
+	}
+}
+
+// remove removes the child from the node.
+func (n *paneNode) remove(child *paneNode) {
+	for i, c := range n.children {
+		if c == child {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+			return
+		}
+	}
+}
+
+// tabLayout is the area of the tab, which is shared by its panes.
+type tabLayout struct {
+	// context of the tab, which is a parent for the contexts of all panes
+	ctx       *AppContext
+	container *tview.Grid
+	root      *paneNode
+	// the pane, which is focused by default
+	lastPane *pane
+	// the pane modules and their position within the tab grid
+	defs    []moduleDefinition
+	cfgs    []ModuleConfig
+	area    Position
+	focused bool
+}
+
+func newTabLayout(ctx *AppContext) *tabLayout {
+	container := tview.NewGrid()
+	container.SetBackgroundColor(tcell.ColorDefault)
+	container.SetRows(0).SetColumns(0)
+	return &tabLayout{ctx: ctx, container: container}
+}
+
+// addModule adds the module to every pane and extends the area of the panes to include the module.
+func (l *tabLayout) addModule(def moduleDefinition, cfg ModuleConfig) {
+	if len(l.defs) == 0 {
+		l.area = cfg.Position
+	} else {
+		right, bottom := maxInt(l.area.Col+l.area.Width, cfg.Col+cfg.Width), maxInt(l.area.Row+l.area.Height, cfg.Row+cfg.Height)
+		l.area.Col, l.area.Row = minInt(l.area.Col, cfg.Col), minInt(l.area.Row, cfg.Row)
+		l.area.Width, l.area.Height = right-l.area.Col, bottom-l.area.Row
+	}
+	l.defs = append(l.defs, def)
+	l.cfgs = append(l.cfgs, cfg)
+	l.focused = l.focused || cfg.Focused
+}
+
+// rebuild updates the view of the layout after the panes have been changed.
+func (l *tabLayout) rebuild(cfg PanesConfig) {
+	l.container.Clear()
+	l.container.AddItem(l.root.build(l.lastPane, cfg), 0, 0, 1, 1, 0, 0, true)
+}
+
+// activePane returns the focused pane, or the last focused one if the focus is outside of the panes.
+func (l *tabLayout) activePane() *pane {
+	for _, p := range l.root.leaves() {
+		if p.view.HasFocus() {
+			return p
+		}
+	}
+	return l.lastPane
+}
+
+// createPane initializes new instances of the pane modules in a new pane of the tab.
+func (app *App) createPane(tabId string, layout *tabLayout) *pane {
+	app.paneCount++
+	paneCtx, scope := layout.ctx.isolated()
+	p := &pane{id: fmt.Sprintf("pane_%d", app.paneCount), view: tview.NewGrid()}
+	p.node = &paneNode{pane: p, size: defaultPaneSize}
+	app.paneScopes[p.id] = scope
+
+	area := layout.area
+	p.view.SetBackgroundColor(tcell.ColorDefault)
+	p.view.SetColumns(gridSlice(app.cfg.Grid.Cols, area.Col, area.Width)...)
+	rows := newGridRows(p.view, area.Row, gridSlice(app.cfg.Grid.Rows, area.Row, area.Height))
+
+	for i, def := range layout.defs {
+		cfg := layout.cfgs[i]
+		view := app.createModule(paneCtx, tabId, p.id, rows, def, def.module(), cfg)
+		if view != nil {
+			p.view.AddItem(view, cfg.Row-area.Row, cfg.Col-area.Col, cfg.Height, cfg.Width, 0, 0, cfg.Focused)
+		}
+	}
+
+	layout.lastPane = p
+	return p
+}
+
+// splitPane adds a new pane next to the active one.
+func (app *App) splitPane(layout *tabLayout, direction SplitDirection) {
+	current := layout.activePane()
+	node := app.createPane(app.currentTab, layout).node
+
+	parent := current.node.parent
+	if parent != nil && parent.direction == direction {
+		// the split already has the direction, so the pane is just added after the current one
+		node.parent = parent
+		for i, child := range parent.children {
+			if child == current.node {
+				parent.children = append(parent.children[:i+1], append([]*paneNode{node}, parent.children[i+1:]...)...)
+				break
+			}
+		}
+	} else {
+		split := &paneNode{direction: direction, size: current.node.size}
+		if parent == nil {
+			layout.root = split
+		} else {
+			parent.replace(current.node, split)
+		}
+		current.node.parent, node.parent = split, split
+		current.node.size = defaultPaneSize
+		split.children = []*paneNode{current.node, node}
+	}
+
+	layout.rebuild(app.cfg.Panes)
+	app.Events().Dispatch(EventSetFocus{Target: node.pane.view})
+}
+
+// closePane removes the active pane and moves the focus to its neighbour.
+func (app *App) closePane(layout *tabLayout) {
+	current := layout.activePane()
+	panes := layout.root.leaves()
+	if len(panes) == 1 {
+		app.Log().Warn("Can not close the last pane of the tab.")
+		return
+	}
+
+	next := panes[0]
+	for i, p := range panes {
+		if p == current && i > 0 {
+			next = panes[i-1]
+		} else if p == current {
+			next = panes[i+1]
+		}
+	}
+
+	parent := current.node.parent
+	parent.remove(current.node)
+	if len(parent.children) == 1 {
+		// a split of a single pane is not needed anymore
+		child := parent.children[0]
+		child.size = parent.size
+		if parent.parent == nil {
+			layout.root, child.parent = child, nil
+		} else {
+			parent.parent.replace(parent, child)
+		}
+	}
+
+	layout.lastPane = next
+	layout.rebuild(app.cfg.Panes)
+	app.Events().Dispatch(EventSetFocus{Target: next.view})
+
+	if scope, ok := app.paneScopes[current.id]; ok {
+		// the modules release their resources, then the scope is closed by the app
+		scope.Dispatch(EventPaneClosed{PaneId: current.id})
+	}
+}
+
+// resizePane changes proportion of the active pane within its split.
+func (app *App) resizePane(layout *tabLayout, delta int) {
+	node := layout.activePane().node
+	if node.parent == nil {
+		return
+	}
+	node.size = maxInt(node.size+delta, 1)
+	layout.rebuild(app.cfg.Panes)
+}
+
+// focusPane moves the focus to the next (or previous, if the step is negative) pane.
+func (app *App) focusPane(layout *tabLayout, step int) {
+	panes := layout.root.leaves()
+	current := 0
+	active := layout.activePane()
+	for i, p := range panes {
+		if p == active {
+			current = i
+		}
+	}
+
+	next := panes[((current+step)%len(panes)+len(panes))%len(panes)]
+	layout.lastPane = next
+	layout.rebuild(app.cfg.Panes)
+	app.Events().Dispatch(EventSetFocus{Target: next.view})
+}
+
+// gridRows are the row sizes of a grid, which can be changed by the modules placed in the grid.
+type gridRows struct {
+	grid *tview.Grid
+	// offset is the row of the app grid, which is the first row of the grid
+	offset   int
+	sizes    []int
+	defaults []int
+}
+
+func newGridRows(grid *tview.Grid, offset int, sizes []int) *gridRows {
+	rows := &gridRows{
+		grid:     grid,
+		offset:   offset,
+		sizes:    append([]int(nil), sizes...),
+		defaults: append([]int(nil), sizes...),
+	}
+	grid.SetRows(rows.sizes...)
+	return rows
+}
+
+// resize changes size of the row of the app grid, the zero size restores the configured one.
+func (r *gridRows) resize(row, size int) {
+	row -= r.offset
+	if row < 0 || row >= len(r.sizes) {
+		return
+	}
+	if size == 0 {
+		size = r.defaults[row]
+	}
+	r.sizes[row] = size
+	r.grid.SetRows(r.sizes...)
+}
+
+// gridSlice returns the sizes of the grid rows (or columns) within the area.
+func gridSlice(sizes []int, from, length int) []int {
+	var slice []int
+	for i := from; i < from+length; i++ {
+		size := 0
+		if i < len(sizes) {
+			size = sizes[i]
+		}
+		slice = append(slice, size)
+	}
+	return slice
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
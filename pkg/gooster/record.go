@@ -0,0 +1,135 @@
+package gooster
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/jumale/gooster/pkg/events"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// startRecording starts writing all dispatched events to the record file.
+func (app *App) startRecording() error {
+	em, ok := app.Events().(TracedEventManager)
+	if !ok {
+		return errors.New("event manager does not support recording")
+	}
+
+	path := app.expandHome(app.cfg.Events.RecordFile)
+	// the recorded events may contain commands and paths, which must not be readable by others
+	file, err := app.Fs().OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.WithMessage(err, "open record file")
+	}
+
+	app.recording = file
+	em.SetTracer(events.NewRecorder(file, app.secretEvents...))
+	app.Log().InfoF("Recording events to %s", path)
+	return nil
+}
+
+// RedactEvents makes the events to be recorded without their fields, e.g. the ones containing passwords.
+func (app *App) RedactEvents(list ...events.IEvent) {
+	app.secretEvents = append(app.secretEvents, list...)
+}
+
+// stopRecording stops writing events, if the recording has been started.
+func (app *App) stopRecording() {
+	if app.recording == nil {
+		return
+	}
+	if em, ok := app.Events().(TracedEventManager); ok {
+		em.SetTracer(nil)
+	}
+	if err := app.recording.Close(); err != nil {
+		app.Log().Error(errors.WithMessage(err, "close record file"))
+	}
+	app.recording = nil
+	app.Log().Info("Recording is stopped")
+}
+
+type ReplayConfig struct {
+	// Speed divides the recorded intervals between events, zero value replays the events without delays.
+	Speed float64
+	// Width and Height define size of the virtual screen.
+	Width  int
+	Height int
+	// Block lists the events, which must not be handled while replaying (e.g. the ones changing the file system).
+	Block []events.IEvent
+}
+
+// Replay runs the app on a virtual screen, dispatches the recorded events
+// and returns the text of the final screen.
+func (app *App) Replay(session io.Reader, cfg ReplayConfig) (string, error) {
+	em, ok := app.Events().(*events.DefaultManager)
+	if !ok {
+		return "", errors.New("event manager does not support replaying")
+	}
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		return "", errors.WithMessage(err, "init virtual screen")
+	}
+	screen.SetSize(cfg.Width, cfg.Height)
+	app.root.SetScreen(screen)
+
+	blocked := make(map[reflect.Type]bool, len(cfg.Block))
+	for _, event := range cfg.Block {
+		blocked[reflect.TypeOf(event)] = true
+	}
+	// the replayed session must not overwrite the real one, nor be recorded as a new one
+	app.cfg.Session.File = ""
+	app.cfg.Events.Record = false
+	// must run before any other subscriber, including the ones with the top priority
+	app.Events().Subscribe(events.HandleWithPrio(float64(events.BeforeAllOtherChanges)+1, func(event events.IEvent) events.IEvent {
+		if blocked[reflect.TypeOf(event)] {
+			app.Log().DebugF("Blocked replayed event %T", event)
+			return nil
+		}
+		return event
+	}))
+
+	if err := app.setup(); err != nil {
+		return "", err
+	}
+	done := make(chan error, 1)
+	go func() { done <- app.root.Run() }()
+
+	replayed, err := events.Replay(em, session, events.ReplayOptions{Speed: cfg.Speed})
+	em.Wait()
+	app.Log().InfoF("Replayed %d events", replayed)
+
+	var text strings.Builder
+	app.syncUpdate(func() {
+		app.root.ForceDraw()
+		cells, width, _ := screen.GetContents()
+		for i, cell := range cells {
+			if len(cell.Runes) > 0 {
+				text.WriteString(string(cell.Runes))
+			} else {
+				text.WriteByte(' ')
+			}
+			if (i+1)%width == 0 {
+				text.WriteByte('\n')
+			}
+		}
+	})
+	app.root.Stop()
+
+	if runErr := <-done; runErr != nil && err == nil {
+		err = errors.WithMessage(runErr, "run app")
+	}
+	return text.String(), err
+}
+
+// syncUpdate runs the function on the UI goroutine and waits until it's done.
+func (app *App) syncUpdate(fn func()) {
+	done := make(chan struct{})
+	app.root.QueueUpdate(func() {
+		defer close(done)
+		fn()
+	})
+	<-done
+}
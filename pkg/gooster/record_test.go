@@ -0,0 +1,31 @@
+package gooster
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	assert := require.New(t)
+
+	t.Run("should not record the replayed events", func(t *testing.T) {
+		tmp, err := ioutil.TempDir("", "gooster")
+		assert.NoError(err)
+		defer func() { assert.NoError(os.RemoveAll(tmp)) }()
+
+		recordFile := filepath.Join(tmp, "events.jsonl")
+		cfg := "app:\n  events:\n    record: true\n    record_file: " + recordFile + "\n"
+		app, err := NewApp(strings.NewReader(cfg), strings.NewReader("app: {}"))
+		assert.NoError(err)
+
+		_, err = app.Replay(strings.NewReader(""), ReplayConfig{Speed: 1, Width: 20, Height: 5})
+		assert.NoError(err)
+
+		_, err = os.Stat(recordFile)
+		assert.True(os.IsNotExist(err), "should not create the record file")
+	})
+}
@@ -0,0 +1,133 @@
+package gooster
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Session is the state of the app, which is kept between restarts.
+type Session struct {
+	Tabs       []TabSession `json:"tabs"`
+	CurrentTab int          `json:"current_tab"`
+}
+
+type TabSession struct {
+	Title string `json:"title"`
+	// Focused is the name of the focused module
+	Focused string       `json:"focused,omitempty"`
+	Modules ModuleStates `json:"modules,omitempty"`
+}
+
+// ModuleStates keeps the session states of the modules by the module names.
+type ModuleStates map[string]json.RawMessage
+
+// NewModuleState encodes state of the module, so that it could be a response to QuerySessionState.
+func NewModuleState(name string, state interface{}) (ModuleStates, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "encode state of module %s", name)
+	}
+	return ModuleStates{name: data}, nil
+}
+
+// Load decodes state of the module into the target, it returns false if there is no state of the module.
+func (s ModuleStates) Load(name string, target interface{}) (bool, error) {
+	data, ok := s[name]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return false, errors.WithMessagef(err, "decode state of module %s", name)
+	}
+	return true, nil
+}
+
+// saveSession writes state of all tabs to the session file.
+func (app *App) saveSession() error {
+	var session Session
+	for i, t := range app.tabs {
+		focused := t.focused
+		if t.id == app.currentTab {
+			session.CurrentTab = i
+			focused = app.focusedModule()
+		}
+
+		// the tab modules and the modules of the active pane respond
+		result, err := app.tabScope(t.id).Request(context.Background(), QuerySessionState{})
+		if err != nil {
+			app.Log().Error(errors.WithMessagef(err, "collect session state of tab '%s'", t.id))
+		}
+		states, _ := result.(ModuleStates)
+		session.Tabs = append(session.Tabs, TabSession{Title: t.title, Focused: focused, Modules: states})
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return errors.WithMessage(err, "encode session")
+	}
+
+	path := app.expandHome(app.cfg.Session.File)
+	if err := app.Fs().MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WithMessage(err, "create session dir")
+	}
+	file, err := app.Fs().Create(path)
+	if err != nil {
+		return errors.WithMessage(err, "create session file")
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return errors.WithMessage(err, "write session file")
+	}
+	app.Log().DebugF("Saved session to %s", path)
+	return nil
+}
+
+// loadSession reads the last saved session, it returns nil if there is no session file.
+func (app *App) loadSession() (*Session, error) {
+	file, err := app.Fs().Open(app.expandHome(app.cfg.Session.File))
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "open session file")
+	}
+	defer file.Close()
+
+	session := &Session{}
+	if err := json.NewDecoder(file).Decode(session); err != nil {
+		return nil, errors.WithMessage(err, "decode session file")
+	}
+	return session, nil
+}
+
+// saveSessionPeriodically saves the session with the configured interval, until the app is stopped.
+// The session is saved on the UI goroutine, which owns the tabs and the focus.
+func (app *App) saveSessionPeriodically() {
+	ticker := time.NewTicker(time.Duration(app.cfg.Session.SaveInterval) * time.Second)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				app.root.QueueUpdate(app.handleEventSaveSession)
+			case <-app.stopped:
+				return
+			}
+		}
+	}()
+}
+
+// expandHome replaces the leading ~ of the path by the user home dir.
+func (app *App) expandHome(path string) string {
+	if strings.HasPrefix(path, "~") {
+		homeDir, _ := app.Fs().UserHomeDir()
+		path = strings.Replace(path, "~", homeDir, 1)
+	}
+	return path
+}
@@ -0,0 +1,105 @@
+package gooster
+
+import (
+	"fmt"
+	"github.com/jumale/gooster/pkg/events"
+	"github.com/rivo/tview"
+	"strings"
+)
+
+const defaultTabTitle = "shell"
+
+type tab struct {
+	id    string
+	title string
+	view  tview.Primitive
+	// name of the module, which is focused when the tab is shown
+	focused string
+}
+
+// tabIndex returns position of the tab, or -1 if it's not found.
+func (app *App) tabIndex(tabId string) int {
+	for i, t := range app.tabs {
+		if t.id == tabId {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextTabId generates a unique ID for a new tab.
+func (app *App) nextTabId() string {
+	return fmt.Sprintf("tab_%d", app.tabCount+1)
+}
+
+// activateTab shows the tab and lets its modules know that the tab is active.
+func (app *App) activateTab(tabId string) {
+	if prev := app.tabIndex(app.currentTab); prev >= 0 && app.currentTab != tabId {
+		// the focus is restored, when the tab is shown again
+		app.tabs[prev].focused = app.focusedModule()
+		if scope, ok := app.tabScopes[app.currentTab]; ok {
+			scope.Dispatch(EventTabDeactivated{TabId: app.currentTab})
+		}
+	}
+
+	t := app.tabs[app.tabIndex(tabId)]
+	app.pages.SwitchToPage(EventAddTab{Id: tabId}.pageId())
+	app.currentTab = tabId
+	app.renderTabBar()
+
+	if scope, ok := app.tabScopes[tabId]; ok {
+		scope.Dispatch(EventTabActivated{TabId: tabId})
+	}
+	var target tview.Primitive = t.view
+	if view := app.moduleByName(t.focused); t.focused != "" && view != nil {
+		target = view
+	}
+	app.Events().Dispatch(EventSetFocus{Target: target})
+}
+
+// switchTab shows the next (or previous, if the step is negative) tab.
+func (app *App) switchTab(step int) {
+	if len(app.tabs) < 2 {
+		return
+	}
+	next := ((app.tabIndex(app.currentTab)+step)%len(app.tabs) + len(app.tabs)) % len(app.tabs)
+	app.Events().Dispatch(EventShowTab{TabId: app.tabs[next].id})
+}
+
+// currentScope returns the event manager of the active pane of the current tab
+// (or of the tab itself, if it has no panes).
+func (app *App) currentScope() events.Manager {
+	return app.tabScope(app.currentTab)
+}
+
+// tabScope returns the event manager of the active pane of the tab (or of the tab itself, if it has no panes).
+func (app *App) tabScope(tabId string) events.Manager {
+	if layout, ok := app.tabLayouts[tabId]; ok {
+		if scope, ok := app.paneScopes[layout.activePane().id]; ok {
+			return scope
+		}
+	}
+	if scope, ok := app.tabScopes[tabId]; ok {
+		return scope
+	}
+	return app.Events()
+}
+
+func (app *App) renderTabBar() {
+	colors := app.cfg.Tabs.Colors
+	var bar strings.Builder
+	for i, t := range app.tabs {
+		fg, bg := colors.Fg, colors.Bg
+		if t.id == app.currentTab {
+			fg, bg = colors.ActiveFg, colors.ActiveBg
+		}
+		_, _ = fmt.Fprintf(&bar, "[#%06x:#%06x] %d %s [-:-] ", fg.Origin().Hex(), bg.Origin().Hex(), i+1, tview.Escape(t.title))
+	}
+	app.tabBar.SetText(bar.String())
+
+	height := 1
+	if app.cfg.Tabs.AutoHide && len(app.tabs) < 2 {
+		height = 0
+	}
+	app.layout.ResizeItem(app.tabBar, height, 0)
+}
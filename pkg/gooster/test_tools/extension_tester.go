@@ -1,7 +1,7 @@
 package testtools
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"github.com/jumale/gooster/pkg/events"
 	"github.com/jumale/gooster/pkg/filesys/fstub"
@@ -20,7 +20,7 @@ type ExtensionTester struct {
 	Target       gooster.Module
 	ConfigReader *ConfigReader
 	Fs           *fstub.Stub
-	logs         *bytes.Buffer
+	logs         *Buffer
 	assert       *require.Assertions
 	events       []events.IEvent
 }
@@ -69,6 +69,12 @@ func (t *ExtensionTester) SendEvent(event events.IEvent) *ExtensionTester {
 	return t
 }
 
+func (t *ExtensionTester) AssertResponse(query events.IEvent, expected interface{}) {
+	actual, err := t.Events().Request(context.Background(), query)
+	t.assert.NoError(err)
+	t.assert.Equal(expected, actual)
+}
+
 func (t *ExtensionTester) AssertFinalEvent(event events.IEvent) {
 	exists := false
 	for _, e := range t.events {
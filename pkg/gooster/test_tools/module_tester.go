@@ -1,7 +1,6 @@
 package testtools
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/jumale/gooster/pkg/events"
@@ -22,8 +21,8 @@ type ModuleTester struct {
 	ConfigReader *ConfigReader
 	Fs           *fstub.Stub
 	screen       *screenStub
-	output       *bytes.Buffer
-	logs         *bytes.Buffer
+	output       *Buffer
+	logs         *Buffer
 	assert       *require.Assertions
 }
 
@@ -38,20 +37,15 @@ func NewModuleTester(t *testing.T, m gooster.Module, cfg interface{}) *ModuleTes
 		Fs:           fs,
 		Module:       m,
 		screen:       NewScreenStub(10, 10),
-		output:       bytes.NewBuffer(nil),
+		output:       &Buffer{},
 		logs:         logs,
 		assert:       require.New(t),
 	}
 
-	ctx.Events().Subscribe(events.HandleWithPrio(events.AfterAllOtherChanges, func(e events.IEvent) events.IEvent {
-		switch event := e.(type) {
-		case gooster.EventOutput:
-			tester.output.Write(event.Data)
-		case gooster.EventDraw:
-			tester.draw()
-		}
-		return e
-	}))
+	// the view is drawn on demand (see View), since the draw events could be dispatched by the module goroutines
+	ctx.Events().Subscribe(
+		events.OnWithPrio(events.AfterAllOtherChanges, func(event gooster.EventOutput) { tester.output.Write(event.Data) }),
+	)
 
 	return tester
 }
@@ -84,8 +78,10 @@ func (t *ModuleTester) PressKey(key tcell.Key, r ...rune) *ModuleTester {
 	return t
 }
 
+// SendEvent dispatches the event and waits until it's handled.
 func (t *ModuleTester) SendEvent(event events.IEvent) *ModuleTester {
 	t.Events().Dispatch(event)
+	t.Events().Wait()
 	return t
 }
 
@@ -166,6 +162,7 @@ func diff(expected, actual string) string {
 }
 
 func (t *ModuleTester) View() string {
+	t.draw()
 	return t.screen.GetView()
 }
 
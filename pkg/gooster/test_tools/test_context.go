@@ -1,15 +1,14 @@
 package testtools
 
 import (
-	"bytes"
 	"github.com/jumale/gooster/pkg/filesys/fstub"
 	"github.com/jumale/gooster/pkg/gooster"
 	"github.com/jumale/gooster/pkg/log"
 	"github.com/pkg/errors"
 )
 
-func TestableContext() (ctx *gooster.AppContext, fs *fstub.Stub, cfg *ConfigReader, logs *bytes.Buffer) {
-	logs = bytes.NewBuffer(nil)
+func TestableContext() (ctx *gooster.AppContext, fs *fstub.Stub, cfg *ConfigReader, logs *Buffer) {
+	logs = &Buffer{}
 	cfg = &ConfigReader{stubs: make(map[string]interface{})}
 	fs = fstub.New(fstub.Config{
 		WorkDir: "/current",
@@ -0,0 +1,210 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"time"
+)
+
+// Entry is a command of the extended history with the details of its run.
+type Entry struct {
+	Cmd      string        `json:"cmd"`
+	Time     time.Time     `json:"time"`
+	Dir      string        `json:"dir,omitempty"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration"`
+	// Session identifies the shell session (e.g. the tab), which has run the command
+	Session string `json:"session,omitempty"`
+	// Imported tells that the command has been imported from a bash history,
+	// so the entry has no details of its run, and it's never accepted by the run filters.
+	Imported bool `json:"imported,omitempty"`
+}
+
+// HasRun tells whether the entry has the details of the command run.
+func (e Entry) HasRun() bool {
+	return !e.Imported
+}
+
+func (e Entry) Succeeded() bool {
+	return e.HasRun() && e.ExitCode == 0
+}
+
+// EntryFilter selects the entries of the extended history.
+type EntryFilter func(entry Entry) bool
+
+// InDir accepts the commands, which have been run in the directory.
+func InDir(dir string) EntryFilter {
+	return func(entry Entry) bool {
+		return entry.HasRun() && entry.Dir == dir
+	}
+}
+
+// Successful accepts the commands, which have finished with zero exit code.
+func Successful() EntryFilter {
+	return Entry.Succeeded
+}
+
+// Record adds the finished command to the extended history.
+func (h *Manager) Record(entry Entry) {
+	if h.Ignores(entry.Cmd) {
+		return
+	}
+	if h.extendedFile == nil {
+		h.addEntries(entry)
+		return
+	}
+	if err := h.writeEntries(entry); err != nil {
+		h.log.Error(err)
+	}
+}
+
+// Entries returns the entries of the extended history, which are accepted by all filters.
+func (h *Manager) Entries(filters ...EntryFilter) []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var found []Entry
+	for _, entry := range h.entries {
+		if acceptsEntry(entry, filters) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// Import reads commands in the bash history format (one command per line) and adds them to the history.
+// The imported commands have no details of their runs.
+func (h *Manager) Import(r io.Reader) (int, error) {
+	var imported []Entry
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if sc.Text() == "" || h.Ignores(sc.Text()) {
+			continue
+		}
+		h.add(sc.Text())
+		imported = append(imported, Entry{Cmd: sc.Text(), Imported: true})
+	}
+	if err := sc.Err(); err != nil {
+		return 0, errors.WithMessage(err, "reading imported history")
+	}
+
+	if h.extendedFile == nil {
+		h.addEntries(imported...)
+	} else if err := h.writeEntries(imported...); err != nil {
+		return 0, err
+	}
+	return len(imported), nil
+}
+
+// Export writes all commands in the bash history format, the oldest first.
+func (h *Manager) Export(w io.Writer) error {
+	for _, cmd := range h.stack {
+		if _, err := io.WriteString(w, cmd+"\n"); err != nil {
+			return errors.WithMessage(err, "writing exported history")
+		}
+	}
+	return nil
+}
+
+func (h *Manager) loadEntries() error {
+	if _, err := h.fs.Stat(h.extendedPath); err != nil {
+		// the file is created with the first entry
+		return nil
+	}
+	entries, err := h.readEntries()
+	if err != nil {
+		return errors.WithMessage(err, "loading extended history file")
+	}
+	for _, entry := range entries {
+		h.add(entry.Cmd)
+	}
+	h.addEntries(entries...)
+	return nil
+}
+
+// mergeEntries adds the entries, which have been written to the extended history file by other instances.
+func (h *Manager) mergeEntries() {
+	unlock, err := h.extendedFile.lock()
+	if err != nil {
+		h.log.Error(err)
+		return
+	}
+	defer unlock()
+
+	entries, err := h.readEntries()
+	if err != nil {
+		h.log.Error(errors.WithMessage(err, "merging extended history file"))
+		return
+	}
+	h.addEntries(entries...)
+}
+
+// readEntries decodes the lines appended to the extended history file since the last read.
+func (h *Manager) readEntries() ([]Entry, error) {
+	lines, err := h.extendedFile.readNew()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, line := range lines {
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			h.log.Error(errors.WithMessage(err, "decoding extended history entry"))
+			continue
+		}
+		if !h.Ignores(entry.Cmd) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// writeEntries appends the entries to the extended history file, after the entries of other instances.
+func (h *Manager) writeEntries(entries ...Entry) error {
+	unlock, err := h.extendedFile.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	merged, err := h.readEntries()
+	if err != nil {
+		return errors.WithMessage(err, "merging extended history file")
+	}
+	h.addEntries(append(merged, entries...)...)
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return errors.WithMessage(err, "encoding extended history entry")
+		}
+		lines[i] = string(data)
+	}
+	if err := h.extendedFile.append(lines...); err != nil {
+		return errors.WithMessage(err, "writing to extended history file")
+	}
+	return errors.WithMessage(h.extendedFile.trim(h.maxSize), "trimming extended history file")
+}
+
+func (h *Manager) addEntries(entries ...Entry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, entries...)
+	if h.maxSize > 0 && len(h.entries) > h.maxSize {
+		h.entries = h.entries[len(h.entries)-h.maxSize:]
+	}
+}
+
+func acceptsEntry(entry Entry, filters []EntryFilter) bool {
+	for _, filter := range filters {
+		if !filter(entry) {
+			return false
+		}
+	}
+	return true
+}
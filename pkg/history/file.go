@@ -0,0 +1,214 @@
+package history
+
+import (
+	"bytes"
+	"github.com/jumale/gooster/pkg/filesys"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// sharedFile is a history file, which is written by several gooster instances and shells at the same time.
+// It remembers how much of the file has been read, so that only the lines appended by others are read on merge.
+type sharedFile struct {
+	path string
+	fs   filesys.FileSys
+	// offset is the size of the content, which has been read or written by the manager
+	offset int
+	// tail is the last line before the offset, it tells whether the content has been rewritten by others
+	tail []byte
+	// lines is the number of the lines in the file
+	lines int
+	// complete tells whether the content ends with a line break (or is empty)
+	complete bool
+	mu       sync.Mutex
+}
+
+func newSharedFile(path string, fs filesys.FileSys) *sharedFile {
+	return &sharedFile{path: path, fs: fs, complete: true}
+}
+
+// historyFileMode keeps the history files readable only by the user, since the commands could contain secrets.
+const historyFileMode = 0600
+
+// trimSlack is the share of max lines, which the file could exceed before it's trimmed,
+// so that the file is not rewritten on every appended line.
+const trimSlack = 0.1
+
+// lock prevents other gooster instances from changing the file, until the returned unlock is called.
+// Shells do not lock their history files, so the lines appended by them are still merged by offset.
+func (f *sharedFile) lock() (unlock func(), err error) {
+	f.mu.Lock()
+	for {
+		file, replaced, err := f.lockFile()
+		if err != nil {
+			f.mu.Unlock()
+			return nil, err
+		}
+		if replaced {
+			// the file has been trimmed by another instance, while waiting for the lock
+			_ = file.Close()
+			continue
+		}
+
+		return func() {
+			// closing the file releases the lock
+			_ = file.Close()
+			f.mu.Unlock()
+		}, nil
+	}
+}
+
+// lockFile opens and locks the file, it tells whether the locked file has been replaced at the path meanwhile.
+func (f *sharedFile) lockFile() (file filesys.File, replaced bool, err error) {
+	file, err = f.fs.OpenFile(f.path, os.O_RDONLY|os.O_CREATE, historyFileMode)
+	if err != nil {
+		return nil, false, errors.WithMessagef(err, "open history file %s", f.path)
+	}
+	// the stubbed files of the tests have no descriptors, so they are not locked
+	osFile, ok := file.(*os.File)
+	if !ok {
+		return file, false, nil
+	}
+	if err := syscall.Flock(int(osFile.Fd()), syscall.LOCK_EX); err != nil {
+		_ = file.Close()
+		return nil, false, errors.WithMessagef(err, "lock history file %s", f.path)
+	}
+
+	locked, err := osFile.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, false, errors.WithMessagef(err, "stat history file %s", f.path)
+	}
+	current, err := f.fs.Stat(f.path)
+	return file, err != nil || !os.SameFile(locked, current), nil
+}
+
+// readNew returns the lines, which have been appended since the last read.
+// It must be called under the lock, unless the file is loaded by the constructor.
+func (f *sharedFile) readNew() ([]string, error) {
+	data, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < f.offset || !bytes.Equal(data[f.offset-len(f.tail):f.offset], f.tail) {
+		// the file has been trimmed by another instance, so it's read again
+		f.offset, f.lines = 0, 0
+	}
+	data = data[f.offset:]
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	f.offset += len(data)
+	f.tail = lastLine(data)
+	f.complete = data[len(data)-1] == '\n'
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	f.lines += len(lines)
+
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		result[i] = string(line)
+	}
+	return result, nil
+}
+
+func (f *sharedFile) read() ([]byte, error) {
+	file, err := f.fs.Open(f.path)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "open history file %s", f.path)
+	}
+	defer func() { _ = file.Close() }()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "read history file %s", f.path)
+	}
+	return data, nil
+}
+
+// append writes the lines to the end of the file. It must be called under the lock, after readNew.
+func (f *sharedFile) append(lines ...string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	var data bytes.Buffer
+	if !f.complete {
+		data.WriteByte('\n')
+	}
+	for _, line := range lines {
+		data.WriteString(line + "\n")
+	}
+
+	file, err := f.fs.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, historyFileMode)
+	if err != nil {
+		return errors.WithMessagef(err, "open history file %s", f.path)
+	}
+	_, err = file.Write(data.Bytes())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "write history file %s", f.path)
+	}
+
+	f.offset += data.Len()
+	f.tail = lastLine(data.Bytes())
+	f.lines += len(lines)
+	f.complete = true
+	return nil
+}
+
+// trim removes the oldest lines, so that the file keeps no more than max lines.
+// The file is trimmed only when it exceeds the max by the slack, and it's replaced at once,
+// so that the history is not lost, if the writing fails.
+// It must be called under the lock, after readNew.
+func (f *sharedFile) trim(max int) error {
+	if max <= 0 || f.lines <= max+int(float64(max)*trimSlack) {
+		return nil
+	}
+
+	data, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= max {
+		return nil
+	}
+	data = bytes.Join(lines[len(lines)-max:], nil)
+
+	// the temp file is created next to the history file, so that it's renamed within the same file system
+	tmpPath := f.path + ".tmp"
+	file, err := f.fs.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, historyFileMode)
+	if err != nil {
+		return errors.WithMessagef(err, "create history file %s", tmpPath)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = f.fs.Rename(tmpPath, f.path)
+	}
+	if err != nil {
+		_ = f.fs.RemoveAll(tmpPath)
+		return errors.WithMessagef(err, "write history file %s", f.path)
+	}
+
+	f.offset, f.tail, f.lines = len(data), lastLine(data), max
+	return nil
+}
+
+// lastLine returns the last line of the data including its line break.
+func lastLine(data []byte) []byte {
+	start := bytes.LastIndexByte(data[:len(data)-1], '\n') + 1
+	return append([]byte(nil), data[start:]...)
+}
@@ -1,27 +1,45 @@
 package history
 
 import (
-	"bufio"
 	"github.com/jumale/gooster/pkg/filesys"
 	"github.com/jumale/gooster/pkg/log"
 	"github.com/pkg/errors"
-	"os"
+	"regexp"
 	"strings"
+	"sync"
 )
 
 type Config struct {
 	HistoryFile string
-	Log         log.Logger
-	FileSys     filesys.FileSys
+	// ExtendedFile keeps the entries with the details of the command runs (as JSON lines),
+	// the commands are still written to the HistoryFile in the bash history format.
+	ExtendedFile string
+	// MaxSize is the max number of commands in the history and the lines in the history files (0 is unlimited).
+	MaxSize int
+	// IgnoreSpace skips the commands starting with a space.
+	IgnoreSpace bool
+	// Ignore are the patterns of the commands, which are not kept in the history (e.g. the ones with secrets).
+	Ignore  []string
+	Log     log.Logger
+	FileSys filesys.FileSys
 }
 
 type Manager struct {
-	set      map[string]struct{}
-	stack    []string
-	index    int
-	filePath string
-	log      log.Logger
-	fs       filesys.FileSys
+	set          map[string]struct{}
+	stack        []string
+	index        int
+	filePath     string
+	extendedPath string
+	// the history files, they are nil if not configured
+	file         *sharedFile
+	extendedFile *sharedFile
+	// the entries of the extended history, they are recorded from the command goroutines
+	entries []Entry
+	mu      sync.Mutex
+	maxSize int
+	ignore  []*regexp.Regexp
+	log     log.Logger
+	fs      filesys.FileSys
 }
 
 func NewManager(cfg Config) (*Manager, error) {
@@ -30,22 +48,36 @@ func NewManager(cfg Config) (*Manager, error) {
 	}
 
 	mng := &Manager{
-		set: make(map[string]struct{}),
-		log: log.EmptyLogger{},
-		fs:  cfg.FileSys,
+		set:     make(map[string]struct{}),
+		maxSize: cfg.MaxSize,
+		log:     log.EmptyLogger{},
+		fs:      cfg.FileSys,
 	}
 	if cfg.Log != nil {
 		mng.log = cfg.Log
 	}
-
-	if strings.HasPrefix(cfg.HistoryFile, "~") {
-		if dir, err := mng.fs.UserHomeDir(); err == nil {
-			cfg.HistoryFile = strings.Replace(cfg.HistoryFile, "~", dir, 1)
+	if cfg.IgnoreSpace {
+		mng.ignore = append(mng.ignore, regexp.MustCompile(`^\s`))
+	}
+	for _, pattern := range cfg.Ignore {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "compiling history ignore pattern '%s'", pattern)
 		}
+		mng.ignore = append(mng.ignore, re)
 	}
-	mng.filePath = cfg.HistoryFile
+
+	mng.filePath = mng.expandHome(cfg.HistoryFile)
 	if mng.filePath != "" {
-		if err := mng.loadHistoryLines(mng.filePath); err != nil {
+		mng.file = newSharedFile(mng.filePath, mng.fs)
+		if err := mng.loadHistoryLines(); err != nil {
+			return nil, err
+		}
+	}
+	mng.extendedPath = mng.expandHome(cfg.ExtendedFile)
+	if mng.extendedPath != "" {
+		mng.extendedFile = newSharedFile(mng.extendedPath, mng.fs)
+		if err := mng.loadEntries(); err != nil {
 			return nil, err
 		}
 	}
@@ -55,17 +87,64 @@ func NewManager(cfg Config) (*Manager, error) {
 	return mng, nil
 }
 
+func (h *Manager) expandHome(path string) string {
+	if strings.HasPrefix(path, "~") {
+		if dir, err := h.fs.UserHomeDir(); err == nil {
+			path = strings.Replace(path, "~", dir, 1)
+		}
+	}
+	return path
+}
+
 func (h *Manager) Add(cmd string) {
-	h.log.DebugF("History add: `%s`", cmd)
 	h.Reset()
-	h.add(cmd)
+	if h.Ignores(cmd) {
+		h.log.DebugF("History ignore: `%s`", cmd)
+		return
+	}
+	h.log.DebugF("History add: `%s`", cmd)
+	// the commands of other instances are merged on write, so they go before the new one
 	h.write(cmd)
+	h.add(cmd)
+}
+
+// Merge adds the commands, which have been written to the history files by other instances and shells.
+func (h *Manager) Merge() {
+	if h.file != nil {
+		unlock, err := h.file.lock()
+		if err != nil {
+			h.log.Error(err)
+			return
+		}
+		defer unlock()
+		h.mergeLines()
+	}
+	if h.extendedFile != nil {
+		h.mergeEntries()
+	}
+}
+
+// Ignores tells whether the command must not be kept in the history.
+func (h *Manager) Ignores(cmd string) bool {
+	for _, re := range h.ignore {
+		if re.MatchString(cmd) {
+			return true
+		}
+	}
+	return false
 }
 
 func (h *Manager) add(cmd string) {
+	if cmd == "" || h.Ignores(cmd) {
+		return
+	}
 	if _, exists := h.set[cmd]; !exists {
 		h.set[cmd] = struct{}{}
 		h.stack = append(h.stack, cmd)
+		for h.maxSize > 0 && len(h.stack) > h.maxSize {
+			delete(h.set, h.stack[0])
+			h.stack = h.stack[1:]
+		}
 		return
 	}
 
@@ -82,40 +161,75 @@ func (h *Manager) Reset() {
 	h.index = -1
 }
 
-func (h *Manager) Prev() string {
-	ln := len(h.stack)
-	if ln == 0 {
-		h.log.Debug("history: there is no prev")
-		return ""
+// Filter selects the entries, which are walked by PrevMatch and NextMatch.
+type Filter func(cmd string) bool
+
+// Prefix accepts the entries starting with the prefix.
+func Prefix(prefix string) Filter {
+	return func(cmd string) bool {
+		return strings.HasPrefix(cmd, prefix)
+	}
+}
+
+// Contains accepts the entries containing the substring.
+func Contains(substr string) Filter {
+	return func(cmd string) bool {
+		return strings.Contains(cmd, substr)
 	}
+}
+
+func (h *Manager) Prev() string {
+	cmd, _ := h.PrevMatch(nil)
+	return cmd
+}
 
+// PrevMatch moves to the previous entry accepted by the filter (nil filter accepts all entries).
+// It loops back to the latest entry after the oldest one.
+// If there is no such entry, it returns false and keeps the current position.
+func (h *Manager) PrevMatch(filter Filter) (string, bool) {
+	ln := len(h.stack)
+	index := h.index
 	if !h.IsActive() {
-		h.index = ln
+		index = ln
 	}
-	h.index--
-	// loop back if reached end of list
-	if h.index < 0 {
-		h.index = ln - 1
+	for i := 0; i < ln; i++ {
+		index--
+		// loop back if reached end of list
+		if index < 0 {
+			index = ln - 1
+		}
+		if filter == nil || filter(h.stack[index]) {
+			h.index = index
+			return h.Current(), true
+		}
 	}
 
-	return h.Current()
+	h.log.Debug("history: there is no prev")
+	return "", false
 }
 
 func (h *Manager) Next() string {
-	ln := len(h.stack)
+	cmd, _ := h.NextMatch(nil)
+	return cmd
+}
+
+// NextMatch moves to the next entry accepted by the filter (nil filter accepts all entries).
+// If there is no such entry, it returns false and resets the position.
+func (h *Manager) NextMatch(filter Filter) (string, bool) {
 	if !h.IsActive() {
 		h.log.Debug("history: list is not active")
-		return ""
+		return "", false
 	}
 
-	h.index++
-	if h.index >= ln {
-		h.Reset()
-		h.log.Debug("history: there is no next")
-		return ""
+	for h.index++; h.index < len(h.stack); h.index++ {
+		if filter == nil || filter(h.Current()) {
+			return h.Current(), true
+		}
 	}
 
-	return h.Current()
+	h.Reset()
+	h.log.Debug("history: there is no next")
+	return "", false
 }
 
 func (h *Manager) Current() string {
@@ -157,37 +271,49 @@ func (h *Manager) Filter(search string) []string {
 	return found
 }
 
-func (h *Manager) loadHistoryLines(filePath string) error {
-	f, err := h.fs.Open(filePath)
+func (h *Manager) loadHistoryLines() error {
+	lines, err := h.file.readNew()
 	if err != nil {
 		return errors.WithMessage(err, "loading bash history file")
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			h.log.Error(errors.WithMessage(err, "closing bash history file"))
-		}
-	}()
-
-	sc := bufio.NewScanner(f)
-	for sc.Scan() {
-		h.add(sc.Text())
+	for _, line := range lines {
+		h.add(line)
 	}
-
 	return nil
 }
 
+// mergeLines adds the lines appended to the history file since the last read.
+// It must be called under the lock of the file.
+func (h *Manager) mergeLines() {
+	lines, err := h.file.readNew()
+	if err != nil {
+		h.log.Error(errors.WithMessage(err, "merging bash history file"))
+		return
+	}
+	for _, line := range lines {
+		h.add(line)
+	}
+}
+
 func (h *Manager) write(cmd string) {
-	f, err := h.fs.OpenFile(h.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// the multi-line commands (e.g. with heredocs) would be read back as separate commands,
+	// so they are kept in the extended history file only
+	if h.file == nil || strings.Contains(cmd, "\n") {
+		return
+	}
+	unlock, err := h.file.lock()
 	if err != nil {
-		h.log.Error(errors.WithMessage(err, "opening hash history file"))
+		h.log.Error(err)
+		return
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			h.log.Error(errors.WithMessage(err, "closing bash history file"))
-		}
-	}()
+	defer unlock()
 
-	if _, err := f.Write([]byte("\n" + cmd)); err != nil {
+	h.mergeLines()
+	if err := h.file.append(cmd); err != nil {
 		h.log.Error(errors.WithMessage(err, "writing to bash history file"))
+		return
+	}
+	if err := h.file.trim(h.maxSize); err != nil {
+		h.log.Error(errors.WithMessage(err, "trimming bash history file"))
 	}
 }
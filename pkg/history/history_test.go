@@ -3,7 +3,10 @@ package history
 import (
 	"github.com/jumale/gooster/pkg/filesys/fstub"
 	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
 	"path"
+	"strings"
 	"testing"
 )
 
@@ -93,7 +96,8 @@ func TestHistory(t *testing.T) {
 			assert.Equal([]string{"foo"}, fs.Get(file).ContentLines())
 
 			mng.Add("bar")
-			assert.Equal([]string{"foo", "bar"}, fs.Get(file).ContentLines())
+			// the lines are terminated, so that the lines appended by shells are not joined with them
+			assert.Equal("foo\nbar\n", fs.Get(file).ContentString())
 		})
 	})
 
@@ -158,6 +162,268 @@ func TestHistory(t *testing.T) {
 		})
 	})
 
+	t.Run("PrevMatch and NextMatch", func(t *testing.T) {
+		t.Run("should walk only the entries accepted by the filter", func(t *testing.T) {
+			mng := create("git status", "ls", "git stash", "make")
+
+			cmd, ok := mng.PrevMatch(Prefix("git"))
+			assert.True(ok)
+			assert.Equal("git stash", cmd)
+
+			cmd, ok = mng.PrevMatch(Prefix("git"))
+			assert.True(ok)
+			assert.Equal("git status", cmd)
+
+			cmd, ok = mng.NextMatch(Prefix("git"))
+			assert.True(ok)
+			assert.Equal("git stash", cmd)
+
+			_, ok = mng.NextMatch(Prefix("git"))
+			assert.False(ok)
+			assert.False(mng.IsActive())
+		})
+
+		t.Run("should keep the position if there is no matching entry", func(t *testing.T) {
+			mng := create("git status", "ls")
+			mng.index = 1
+
+			_, ok := mng.PrevMatch(Contains("make"))
+			assert.False(ok)
+			assert.Equal(1, mng.index)
+		})
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		t.Run("should find commands containing the query characters in order", func(t *testing.T) {
+			mng := create("git status", "go test", "make", "Git commit")
+			var found []string
+			for _, match := range mng.Search("gt") {
+				found = append(found, match.Cmd)
+			}
+			assert.ElementsMatch([]string{"git status", "go test", "Git commit"}, found)
+		})
+
+		t.Run("should return positions of the matched characters", func(t *testing.T) {
+			mng := create("go test")
+			matches := mng.Search("gt")
+			assert.Len(matches, 1)
+			assert.Equal([]int{0, 3}, matches[0].Positions)
+		})
+
+		t.Run("should rank consecutive characters and word starts higher", func(t *testing.T) {
+			mng := create("go test --short", "git stash", "gst")
+			matches := mng.Search("gst")
+			assert.Equal("gst", matches[0].Cmd)
+			assert.Equal("git stash", matches[1].Cmd)
+			assert.Equal("go test --short", matches[2].Cmd)
+		})
+
+		t.Run("should put recent commands first among equally ranked ones", func(t *testing.T) {
+			mng := create("ls -a", "ls -l")
+			matches := mng.Search("ls")
+			assert.Equal("ls -l", matches[0].Cmd)
+			assert.Equal("ls -a", matches[1].Cmd)
+		})
+
+		t.Run("should return all commands for empty query", func(t *testing.T) {
+			mng := create("foo", "bar")
+			assert.Len(mng.Search(""), 2)
+		})
+	})
+
+	t.Run("Entries", func(t *testing.T) {
+		createExtended := func() (*Manager, *fstub.Stub) {
+			fs := newFs()
+			mng, err := NewManager(Config{ExtendedFile: "history.jsonl", FileSys: fs})
+			assert.NoError(err)
+			return mng, fs
+		}
+
+		t.Run("should record entries to the extended file", func(t *testing.T) {
+			mng, fs := createExtended()
+			mng.Record(Entry{Cmd: "make", Dir: "/foo", ExitCode: 2})
+			mng.Record(Entry{Cmd: "ls", Dir: "/bar"})
+
+			assert.Len(mng.Entries(), 2)
+			assert.Equal([]string{
+				`{"cmd":"make","time":"0001-01-01T00:00:00Z","dir":"/foo","exit_code":2,"duration":0}`,
+				`{"cmd":"ls","time":"0001-01-01T00:00:00Z","dir":"/bar","exit_code":0,"duration":0}`,
+				"",
+			}, fs.Get("history.jsonl").ContentLines())
+		})
+
+		t.Run("should load entries from the extended file", func(t *testing.T) {
+			fs := newFs()
+			fs.Root().Add("history.jsonl", fstub.NewFile(
+				`{"cmd":"make","dir":"/foo","exit_code":2}`,
+				`{"cmd":"ls","dir":"/bar","exit_code":0}`,
+			))
+
+			mng, err := NewManager(Config{ExtendedFile: "history.jsonl", FileSys: fs})
+			assert.NoError(err)
+			assert.Equal([]string{"make", "ls"}, mng.stack)
+			assert.Equal([]Entry{{Cmd: "ls", Dir: "/bar"}}, mng.Entries(Successful()))
+			assert.Equal([]Entry{{Cmd: "make", Dir: "/foo", ExitCode: 2}}, mng.Entries(InDir("/foo")))
+		})
+
+		t.Run("should search only commands of the accepted entries", func(t *testing.T) {
+			mng, _ := createExtended()
+			mng.Add("make")
+			mng.Add("make test")
+			mng.Record(Entry{Cmd: "make", Dir: "/foo"})
+			mng.Record(Entry{Cmd: "make test", Dir: "/bar"})
+
+			matches := mng.Search("make", InDir("/bar"))
+			assert.Len(matches, 1)
+			assert.Equal("make test", matches[0].Cmd)
+		})
+
+		t.Run("should import commands in bash history format", func(t *testing.T) {
+			mng, _ := createExtended()
+			count, err := mng.Import(strings.NewReader("foo\n\nbar\n"))
+			assert.NoError(err)
+			assert.Equal(2, count)
+			assert.Equal([]string{"foo", "bar"}, mng.stack)
+			assert.Len(mng.Entries(), 2)
+		})
+
+		t.Run("should not accept the imported commands by the run filters", func(t *testing.T) {
+			mng, _ := createExtended()
+			_, err := mng.Import(strings.NewReader("foo\n"))
+			assert.NoError(err)
+			mng.Record(Entry{Cmd: "bar"})
+
+			assert.Equal([]Entry{{Cmd: "foo", Imported: true}, {Cmd: "bar"}}, mng.Entries())
+			assert.Equal([]Entry{{Cmd: "bar"}}, mng.Entries(Successful()))
+			assert.Equal([]Entry{{Cmd: "bar"}}, mng.Entries(InDir("")))
+		})
+
+		t.Run("should export commands in bash history format", func(t *testing.T) {
+			mng := create("foo", "bar")
+			out := &strings.Builder{}
+			assert.NoError(mng.Export(out))
+			assert.Equal("foo\nbar\n", out.String())
+		})
+	})
+
+	t.Run("Shared files", func(t *testing.T) {
+		t.Run("should merge commands appended by other instances", func(t *testing.T) {
+			fs := newFs()
+			fs.Root().Add("history.txt", fstub.NewFile("foo\n"))
+			mng, err := NewManager(Config{HistoryFile: "history.txt", FileSys: fs})
+			assert.NoError(err)
+
+			_, _ = fs.Get("history.txt").Write([]byte("bar\n"))
+			mng.Merge()
+			assert.Equal([]string{"foo", "bar"}, mng.stack)
+
+			_, _ = fs.Get("history.txt").Write([]byte("baz\n"))
+			mng.Add("qux")
+			assert.Equal([]string{"foo", "bar", "baz", "qux"}, mng.stack)
+			assert.Equal("foo\nbar\nbaz\nqux\n", fs.Get("history.txt").ContentString())
+		})
+
+		t.Run("should not keep ignored commands", func(t *testing.T) {
+			fs := newFs()
+			fs.Root().Add("history.txt", fstub.NewFile("foo\n"))
+			mng, err := NewManager(Config{
+				HistoryFile: "history.txt",
+				IgnoreSpace: true,
+				Ignore:      []string{`PASSWORD=`},
+				FileSys:     fs,
+			})
+			assert.NoError(err)
+
+			mng.Add(" secret")
+			mng.Add("PASSWORD=123 login")
+			mng.Record(Entry{Cmd: " secret"})
+			mng.Add("bar")
+			assert.Equal([]string{"foo", "bar"}, mng.stack)
+			assert.Equal("foo\nbar\n", fs.Get("history.txt").ContentString())
+			assert.Empty(mng.Entries())
+		})
+
+		t.Run("should keep multi-line commands out of the bash history file", func(t *testing.T) {
+			fs := newFs()
+			fs.Root().Add("history.txt", fstub.NewFile("foo\n"))
+			mng, err := NewManager(Config{HistoryFile: "history.txt", FileSys: fs})
+			assert.NoError(err)
+
+			mng.Add("cat <<EOF\nbar\nEOF")
+			assert.Equal([]string{"foo", "cat <<EOF\nbar\nEOF"}, mng.stack)
+			assert.Equal("foo\n", fs.Get("history.txt").ContentString())
+		})
+
+		t.Run("should return error for invalid ignore pattern", func(t *testing.T) {
+			_, err := NewManager(Config{Ignore: []string{"("}, FileSys: newFs()})
+			assert.Error(err)
+		})
+
+		t.Run("should trim history to the max size", func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "history")
+			assert.NoError(err)
+			defer os.RemoveAll(dir)
+			file := path.Join(dir, "history.txt")
+			assert.NoError(ioutil.WriteFile(file, []byte("foo\nbar\n"), 0644))
+
+			mng, err := NewManager(Config{HistoryFile: file, MaxSize: 2})
+			assert.NoError(err)
+			other, err := NewManager(Config{HistoryFile: file, MaxSize: 2})
+			assert.NoError(err)
+
+			mng.Add("baz")
+			assert.Equal([]string{"bar", "baz"}, mng.stack)
+			content, err := ioutil.ReadFile(file)
+			assert.NoError(err)
+			assert.Equal("bar\nbaz\n", string(content))
+
+			// the trimmed file is read again by other instances
+			other.Add("qux")
+			assert.Equal([]string{"baz", "qux"}, other.stack)
+			content, err = ioutil.ReadFile(file)
+			assert.NoError(err)
+			assert.Equal("baz\nqux\n", string(content))
+		})
+
+		t.Run("should trim history only when it exceeds the max size by the slack", func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "history")
+			assert.NoError(err)
+			defer os.RemoveAll(dir)
+			file := path.Join(dir, "history.txt")
+			assert.NoError(ioutil.WriteFile(file, []byte(strings.Repeat("foo\n", 10)), 0644))
+
+			mng, err := NewManager(Config{HistoryFile: file, MaxSize: 10})
+			assert.NoError(err)
+
+			mng.Add("bar")
+			content, err := ioutil.ReadFile(file)
+			assert.NoError(err)
+			assert.Equal(strings.Repeat("foo\n", 10)+"bar\n", string(content))
+
+			mng.Add("baz")
+			content, err = ioutil.ReadFile(file)
+			assert.NoError(err)
+			assert.Equal(strings.Repeat("foo\n", 8)+"bar\nbaz\n", string(content))
+			_, err = os.Stat(file + ".tmp")
+			assert.True(os.IsNotExist(err), "should remove the temp file")
+		})
+
+		t.Run("should create history file readable only by the user", func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "history")
+			assert.NoError(err)
+			defer os.RemoveAll(dir)
+			file := path.Join(dir, "history.txt")
+
+			mng, err := NewManager(Config{ExtendedFile: file})
+			assert.NoError(err)
+			mng.Record(Entry{Cmd: "foo"})
+
+			info, err := os.Stat(file)
+			assert.NoError(err)
+			assert.Equal(os.FileMode(0600), info.Mode().Perm())
+		})
+	})
+
 	t.Run("Reset", func(t *testing.T) {
 		t.Run("should reset index", func(t *testing.T) {
 			mng := create()
@@ -0,0 +1,116 @@
+package history
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// bonus for a character, which follows the previous matched one
+	consecutiveBonus = 5
+	// bonus for a character at the start of a word
+	wordStartBonus = 3
+)
+
+// Match is a history entry, which matches the search query.
+type Match struct {
+	Cmd string
+	// Positions are the byte offsets of the matched characters in the command
+	Positions []int
+	Score     int
+}
+
+// Search finds the entries, which contain all characters of the query in the same order (case-insensitive).
+// The matches are ranked: consecutive characters and characters at word starts score higher,
+// gaps between the characters score lower. The recent entries go first among the equally ranked ones.
+// An empty query matches all entries. The filters accept the commands, which have at least one run
+// in the extended history, accepted by all of them.
+func (h *Manager) Search(query string, filters ...EntryFilter) []Match {
+	var accepted map[string]bool
+	if len(filters) > 0 {
+		accepted = make(map[string]bool)
+		for _, entry := range h.Entries(filters...) {
+			accepted[entry.Cmd] = true
+		}
+	}
+
+	query = strings.ToLower(query)
+	var matches []Match
+	for i := len(h.stack) - 1; i >= 0; i-- {
+		if accepted != nil && !accepted[h.stack[i]] {
+			continue
+		}
+		if match, ok := fuzzyMatch(h.stack[i], query); ok {
+			matches = append(matches, match)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// fuzzyMatch finds the best match of the query, trying every occurrence of its first character.
+func fuzzyMatch(cmd, query string) (best Match, found bool) {
+	if query == "" {
+		return Match{Cmd: cmd}, true
+	}
+	first, _ := utf8.DecodeRuneInString(query)
+	for start, r := range cmd {
+		if unicode.ToLower(r) != first {
+			continue
+		}
+		if match, ok := matchFrom(cmd, query, start); ok && (!found || match.Score > best.Score) {
+			best, found = match, true
+		}
+	}
+	return best, found
+}
+
+// matchFrom greedily matches the query characters, starting from the offset of the command.
+func matchFrom(cmd, query string, offset int) (Match, bool) {
+	match := Match{Cmd: cmd}
+	pos := offset
+	for _, q := range query {
+		idx := indexFold(cmd, q, pos)
+		if idx < 0 {
+			return match, false
+		}
+
+		match.Score++
+		if len(match.Positions) > 0 && idx == pos {
+			match.Score += consecutiveBonus
+		} else if len(match.Positions) > 0 {
+			// every skipped character lowers the score
+			match.Score -= utf8.RuneCountInString(cmd[pos:idx])
+		}
+		if isWordStart(cmd, idx) {
+			match.Score += wordStartBonus
+		}
+
+		match.Positions = append(match.Positions, idx)
+		_, size := utf8.DecodeRuneInString(cmd[idx:])
+		pos = idx + size
+	}
+	return match, true
+}
+
+// indexFold returns offset of the first case-insensitive occurrence of the lower case rune after the offset, or -1.
+func indexFold(s string, lower rune, offset int) int {
+	for i, r := range s[offset:] {
+		if unicode.ToLower(r) == lower {
+			return offset + i
+		}
+	}
+	return -1
+}
+
+func isWordStart(s string, idx int) bool {
+	if idx == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:idx])
+	return unicode.IsSpace(r) || strings.ContainsRune("/-_.=|;&", r)
+}